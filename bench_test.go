@@ -12,13 +12,18 @@ import (
 )
 
 func clearCaches() {
-	packerCache.Lock()
-	defer packerCache.Unlock()
-	unpackerCache.Lock()
-	defer unpackerCache.Unlock()
-
-	packerCache.m = make(map[reflect.Type]cachedPacker)
-	unpackerCache.m = make(map[reflect.Type]unpacker)
+	packerCache.Range(func(key, _ interface{}) bool {
+		packerCache.Delete(key)
+		return true
+	})
+	unpackerCache.Range(func(key, _ interface{}) bool {
+		unpackerCache.Delete(key)
+		return true
+	})
+	planCache.Range(func(key, _ interface{}) bool {
+		planCache.Delete(key)
+		return true
+	})
 }
 
 func BenchmarkBaseline(b *testing.B) {
@@ -31,7 +36,7 @@ func BenchmarkBool1Field(b *testing.B) {
 		F1 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -45,7 +50,7 @@ func BenchmarkBool2Fields(b *testing.B) {
 		F1, F2 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -59,7 +64,7 @@ func BenchmarkBool4Fields(b *testing.B) {
 		F1, F2, F3, F4 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -73,7 +78,7 @@ func BenchmarkBool8Fields(b *testing.B) {
 		F1, F2, F3, F4, F5, F6, F7, F8 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -87,7 +92,7 @@ func BenchmarkBool9Fields(b *testing.B) {
 		F1, F2, F3, F4, F5, F6, F7, F8, F9 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 2)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -101,7 +106,7 @@ func BenchmarkBool16Fields(b *testing.B) {
 		F1, F2, F3, F4, F5, F6, F7, F8, F9, F10, F11, F12, F13, F14, F15, F16 bool
 	}
 
-	p, _ := makePackerWrapper(reflect.TypeOf(typ{}))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(typ{}), LSBFirst)
 	bytes := make([]byte, 2)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -157,6 +162,31 @@ func BenchmarkNoLockContention(b *testing.B) {
 	}
 }
 
+func BenchmarkPackParallel(b *testing.B) {
+	var s benchSample
+	Pack(make([]byte, PackedSizeof(s)), s)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		bytes := make([]byte, PackedSizeof(s))
+		for pb.Next() {
+			Pack(bytes, &s)
+		}
+	})
+}
+
+func BenchmarkUnpackParallel(b *testing.B) {
+	var s benchSample
+	Unpack(make([]byte, PackedSizeof(s)), &s)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		bytes := make([]byte, PackedSizeof(s))
+		var s benchSample
+		for pb.Next() {
+			Unpack(bytes, &s)
+		}
+	})
+}
+
 func BenchmarkPackExported1Field(b *testing.B) {
 	type typ struct {
 		F1 uint8
@@ -178,7 +208,7 @@ func BenchmarkPackBackend1Field(b *testing.B) {
 
 	t := typ{}
 	bytes := []byte{0}
-	p, _, _ := makePacker(0, reflect.TypeOf(t))
+	p, _, _ := makePacker(LSBFirst, "", 0, reflect.TypeOf(t))
 	v := reflect.ValueOf(t)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -207,7 +237,7 @@ func BenchmarkPackBackend2Fields(b *testing.B) {
 
 	t := typ{}
 	bytes := []byte{0, 0}
-	p, _, _ := makePacker(0, reflect.TypeOf(t))
+	p, _, _ := makePacker(LSBFirst, "", 0, reflect.TypeOf(t))
 	v := reflect.ValueOf(t)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -236,7 +266,7 @@ func BenchmarkPackBackend4Fields(b *testing.B) {
 
 	t := typ{}
 	bytes := []byte{0, 0, 0, 0}
-	p, _, _ := makePacker(0, reflect.TypeOf(t))
+	p, _, _ := makePacker(LSBFirst, "", 0, reflect.TypeOf(t))
 	v := reflect.ValueOf(t)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -265,7 +295,7 @@ func BenchmarkPackBackend8Fields(b *testing.B) {
 
 	t := typ{}
 	bytes := []byte{0, 0, 0, 0, 0, 0, 0, 0}
-	p, _, _ := makePacker(0, reflect.TypeOf(t))
+	p, _, _ := makePacker(LSBFirst, "", 0, reflect.TypeOf(t))
 	v := reflect.ValueOf(t)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -273,6 +303,11 @@ func BenchmarkPackBackend8Fields(b *testing.B) {
 	}
 }
 
+// The BenchmarkMake benchmarks use makePacker
+// and makeUnpacker, which build a new plan each
+// time rather than using the caches, so that
+// they do not measure a cache hit.
+
 func BenchmarkMakeEmptyPacker(b *testing.B) {
 	type typ struct {
 	}
@@ -280,7 +315,7 @@ func BenchmarkMakeEmptyPacker(b *testing.B) {
 	t := reflect.TypeOf(typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makePackerWrapper(t)
+		makePacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -292,7 +327,7 @@ func BenchmarkMakePacker1Field(b *testing.B) {
 	t := reflect.TypeOf(typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makePackerWrapper(t)
+		makePacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -304,7 +339,7 @@ func BenchmarkMakePacker2Fields(b *testing.B) {
 	t := reflect.TypeOf(typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makePackerWrapper(t)
+		makePacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -316,7 +351,7 @@ func BenchmarkMakePacker4Fields(b *testing.B) {
 	t := reflect.TypeOf(typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makePackerWrapper(t)
+		makePacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -328,7 +363,7 @@ func BenchmarkMakePacker8Fields(b *testing.B) {
 	t := reflect.TypeOf(typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makePackerWrapper(t)
+		makePacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -339,7 +374,7 @@ func BenchmarkMakeEmptyUnpacker(b *testing.B) {
 	t := reflect.TypeOf(&typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeUnpackerWrapper(t)
+		makeUnpacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -351,7 +386,7 @@ func BenchmarkMakeUnpacker1Field(b *testing.B) {
 	t := reflect.TypeOf(&typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeUnpackerWrapper(t)
+		makeUnpacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -363,7 +398,7 @@ func BenchmarkMakeUnpacker2Fields(b *testing.B) {
 	t := reflect.TypeOf(&typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeUnpackerWrapper(t)
+		makeUnpacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -375,7 +410,7 @@ func BenchmarkMakeUnpacker4Fields(b *testing.B) {
 	t := reflect.TypeOf(&typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeUnpackerWrapper(t)
+		makeUnpacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -387,7 +422,7 @@ func BenchmarkMakeUnpacker8Fields(b *testing.B) {
 	t := reflect.TypeOf(&typ{})
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		makeUnpackerWrapper(t)
+		makeUnpacker(LSBFirst, "", 0, t)
 	}
 }
 
@@ -396,7 +431,7 @@ func BenchmarkUseEmptyPacker(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -411,7 +446,7 @@ func BenchmarkUsePacker1Field(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -426,7 +461,7 @@ func BenchmarkUsePacker2Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 2)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -441,7 +476,7 @@ func BenchmarkUsePacker4Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 4)
 	val := reflect.ValueOf(&typ{}).Elem()
 	b.ResetTimer()
@@ -456,7 +491,7 @@ func BenchmarkUsePacker8Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 8)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -471,7 +506,7 @@ func BenchmarkUsePacker8FieldsSigned(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 8)
 	val := reflect.ValueOf(typ{})
 	b.ResetTimer()
@@ -485,7 +520,7 @@ func BenchmarkUseEmptyUnpacker(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -500,7 +535,7 @@ func BenchmarkUseUnpacker1Field(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 1)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -515,7 +550,7 @@ func BenchmarkUseUnpacker2Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 2)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -530,7 +565,7 @@ func BenchmarkUseUnpacker4Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 4)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -545,7 +580,7 @@ func BenchmarkUseUnpacker8Fields(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 8)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -560,7 +595,7 @@ func BenchmarkUseUnpacker8FieldsSigned(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(&typ{})
-	u := makeUnpackerWrapper(t)
+	u, _ := makeUnpackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 8)
 	val := reflect.ValueOf(&typ{})
 	b.ResetTimer()
@@ -576,7 +611,7 @@ func BenchmarkNested1Level(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(&typ{}).Elem()
 	b.ResetTimer()
@@ -594,7 +629,7 @@ func BenchmarkNested2Levels(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(&typ{}).Elem()
 	b.ResetTimer()
@@ -616,7 +651,7 @@ func BenchmarkNested4Levels(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(&typ{}).Elem()
 	b.ResetTimer()
@@ -646,7 +681,7 @@ func BenchmarkNested8Levels(b *testing.B) {
 	}
 
 	t := reflect.TypeOf(typ{})
-	p, _ := makePackerWrapper(t)
+	p, _, _ := makePackerWrapper(t, LSBFirst)
 	bytes := make([]byte, 0)
 	val := reflect.ValueOf(&typ{}).Elem()
 	b.ResetTimer()
@@ -654,3 +689,89 @@ func BenchmarkNested8Levels(b *testing.B) {
 		p(bytes, val)
 	}
 }
+
+type benchSample struct {
+	Channel uint8  `gopack:"4"`
+	Value   uint16 `gopack:"12"`
+	Valid   bool
+	Delta   int8 `gopack:"7"`
+}
+
+var benchSamples = make([]benchSample, 1000)
+
+func BenchmarkPackLoop(b *testing.B) {
+	bytes := make([]byte, PackedSliceSizeof(benchSamples))
+	n := PackedSizeof(benchSample{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, s := range benchSamples {
+			Pack(bytes[j*n:], s)
+		}
+	}
+}
+
+func BenchmarkPackSlice(b *testing.B) {
+	bytes := make([]byte, PackedSliceSizeof(benchSamples))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PackSlice(bytes, benchSamples)
+	}
+}
+
+func BenchmarkPackSliceContiguous(b *testing.B) {
+	c := Config{Contiguous: true}
+	bytes := make([]byte, c.PackedSliceSizeof(benchSamples))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.PackSlice(bytes, benchSamples)
+	}
+}
+
+func BenchmarkUnpackLoop(b *testing.B) {
+	bytes := make([]byte, PackedSliceSizeof(benchSamples))
+	n := PackedSizeof(benchSample{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range benchSamples {
+			Unpack(bytes[j*n:], &benchSamples[j])
+		}
+	}
+}
+
+func BenchmarkUnpackSlice(b *testing.B) {
+	bytes := make([]byte, PackedSliceSizeof(benchSamples))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UnpackSlice(bytes, benchSamples)
+	}
+}
+
+func BenchmarkUnpackSliceContiguous(b *testing.B) {
+	c := Config{Contiguous: true}
+	bytes := make([]byte, c.PackedSliceSizeof(benchSamples))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.UnpackSlice(bytes, benchSamples)
+	}
+}
+
+var benchRecord = varRecord{8, make([]varEntry, 8), 0}
+
+func BenchmarkPackVarLen(b *testing.B) {
+	bytes := make([]byte, PackedSizeof(benchRecord))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Pack(bytes, benchRecord)
+	}
+}
+
+func BenchmarkUnpackVarLen(b *testing.B) {
+	bytes := AppendPack(nil, benchRecord)
+	var val varRecord
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Unpack(bytes, &val)
+	}
+}
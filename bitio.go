@@ -0,0 +1,196 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+// A BitWriter writes values of arbitrary bit
+// widths to a byte slice, one after another,
+// starting at bit offset 0. It is useful for
+// formats which cannot be described by struct
+// types.
+//
+//	w := NewBitWriter(b, MSBFirst)
+//	w.WriteUnsigned(4, 4) // version
+//	w.WriteBool(urgent)
+//	w.Align()
+//
+// Writes replace the bits they cover, leaving
+// the rest of the slice unmodified. Methods
+// which fail leave the position unchanged.
+type BitWriter struct {
+	b     []byte
+	order BitOrder
+	pos   uint64
+}
+
+// NewBitWriter returns a BitWriter which
+// writes to b, laid out in order.
+func NewBitWriter(b []byte, order BitOrder) *BitWriter {
+	return &BitWriter{b: b, order: order}
+}
+
+// Pos returns the bit offset at which
+// the next value will be written.
+func (w *BitWriter) Pos() int { return int(w.pos) }
+
+// Bytes returns the bytes of the slice
+// which have been at least partially
+// written or skipped over.
+func (w *BitWriter) Bytes() []byte { return w.b[:bitsToBytes(w.pos)] }
+
+// WriteUnsigned writes u in width bits, where
+// width is in the range [1, 64]. If u does not
+// fit in width bits, it returns an error of
+// kind Overflow.
+func (w *BitWriter) WriteUnsigned(u uint64, width int) error {
+	if err := checkBits(w.b, w.pos, width); err != nil {
+		return err
+	}
+	if width < 64 && u>>uint(width) != 0 {
+		return newFieldError(Overflow, "", w.pos, uint64(width), "value out of range: max %v; got %v", uint64(1)<<uint(width)-1, u)
+	}
+	w.put(u, width)
+	return nil
+}
+
+// WriteSigned writes i in width bits as a two's
+// complement integer, where width is in the
+// range [1, 64]. If i does not fit in width
+// bits, it returns an error of kind Overflow.
+func (w *BitWriter) WriteSigned(i int64, width int) error {
+	if err := checkBits(w.b, w.pos, width); err != nil {
+		return err
+	}
+	if width < 64 {
+		max := int64(1)<<uint(width-1) - 1
+		min := -max - 1
+		if i > max || i < min {
+			return newFieldError(Overflow, "", w.pos, uint64(width), "value out of range: max %v, min %v; got %v", max, min, i)
+		}
+	}
+	w.put(uint64(i), width)
+	return nil
+}
+
+// WriteBool writes v as a single bit.
+func (w *BitWriter) WriteBool(v bool) error {
+	var u uint64
+	if v {
+		u = 1
+	}
+	return w.WriteUnsigned(u, 1)
+}
+
+func (w *BitWriter) put(u uint64, width int) {
+	clearBits(w.order, w.b, w.pos, uint64(width))
+	w.order.PutBits(w.b, int(w.pos), width, u)
+	w.pos += uint64(width)
+}
+
+// Skip advances the position by n bits
+// without modifying them.
+func (w *BitWriter) Skip(n int) error {
+	if err := checkSkip(w.b, w.pos, n); err != nil {
+		return err
+	}
+	w.pos += uint64(n)
+	return nil
+}
+
+// Align advances the position to
+// the next byte boundary, if it is
+// not already on one, without
+// modifying the bits skipped.
+func (w *BitWriter) Align() {
+	w.pos = 8 * uint64(bitsToBytes(w.pos))
+}
+
+// A BitReader reads values of arbitrary bit
+// widths from a byte slice, one after another,
+// starting at bit offset 0. It is the inverse
+// of BitWriter. Methods which fail leave the
+// position unchanged.
+type BitReader struct {
+	b     []byte
+	order BitOrder
+	pos   uint64
+}
+
+// NewBitReader returns a BitReader which
+// reads from b, laid out in order.
+func NewBitReader(b []byte, order BitOrder) *BitReader {
+	return &BitReader{b: b, order: order}
+}
+
+// Pos returns the bit offset from which
+// the next value will be read.
+func (r *BitReader) Pos() int { return int(r.pos) }
+
+// ReadUnsigned reads an unsigned integer of
+// width bits, where width is in the range
+// [1, 64].
+func (r *BitReader) ReadUnsigned(width int) (uint64, error) {
+	if err := checkBits(r.b, r.pos, width); err != nil {
+		return 0, err
+	}
+	u := r.order.GetBits(r.b, int(r.pos), width)
+	r.pos += uint64(width)
+	return u, nil
+}
+
+// ReadSigned reads a two's complement integer
+// of width bits, where width is in the range
+// [1, 64].
+func (r *BitReader) ReadSigned(width int) (int64, error) {
+	u, err := r.ReadUnsigned(width)
+	if err != nil {
+		return 0, err
+	}
+	shift := uint(64 - width)
+	return int64(u<<shift) >> shift, nil
+}
+
+// ReadBool reads a single bit.
+func (r *BitReader) ReadBool() (bool, error) {
+	u, err := r.ReadUnsigned(1)
+	return u == 1, err
+}
+
+// Skip advances the position by n bits.
+func (r *BitReader) Skip(n int) error {
+	if err := checkSkip(r.b, r.pos, n); err != nil {
+		return err
+	}
+	r.pos += uint64(n)
+	return nil
+}
+
+// Align advances the position to
+// the next byte boundary, if it is
+// not already on one.
+func (r *BitReader) Align() {
+	r.pos = 8 * uint64(bitsToBytes(r.pos))
+}
+
+// Returns an error if width is out of range,
+// or if b does not hold width bits at pos
+func checkBits(b []byte, pos uint64, width int) error {
+	if width < 1 || width > 64 {
+		return newFieldError(BadWidth, "", pos, 0, "bad bit width (%d)", width)
+	}
+	if pos+uint64(width) > 8*uint64(len(b)) {
+		return newFieldError(ShortBuffer, "", pos, uint64(width), "buffer too small (%v; need %v)", len(b), bitsToBytes(pos+uint64(width)))
+	}
+	return nil
+}
+
+func checkSkip(b []byte, pos uint64, n int) error {
+	if n < 0 {
+		return newFieldError(BadWidth, "", pos, 0, "negative skip (%d)", n)
+	}
+	if pos+uint64(n) > 8*uint64(len(b)) {
+		return newFieldError(ShortBuffer, "", pos, uint64(n), "buffer too small (%v; need %v)", len(b), bitsToBytes(pos+uint64(n)))
+	}
+	return nil
+}
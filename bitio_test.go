@@ -0,0 +1,166 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestBitWriter(t *testing.T) {
+	// The ipv4Prefix and header examples,
+	// written by hand
+	b := []byte{0xFF, 0xFF, 0xFF}
+	w := NewBitWriter(b, MSBFirst)
+	w.WriteUnsigned(4, 4)
+	w.WriteUnsigned(5, 4)
+	w.WriteSigned(-2, 3)
+	w.Skip(2)
+	w.WriteBool(false)
+	w.Align()
+	if w.Pos() != 16 {
+		t.Errorf("Expected position 16; got %v", w.Pos())
+	}
+	w.WriteUnsigned(0, 3)
+	expect := []byte{0x45, 0xDB, 0x1F}
+	if !bytes.Equal(b, expect) {
+		t.Errorf("Expected %#v; got %#v", expect, b)
+	}
+	if got := w.Bytes(); !bytes.Equal(got, b) {
+		t.Errorf("Expected %#v; got %#v", b, got)
+	}
+
+	r := NewBitReader(b, MSBFirst)
+	u1, _ := r.ReadUnsigned(4)
+	u2, _ := r.ReadUnsigned(4)
+	i, _ := r.ReadSigned(3)
+	r.Skip(2)
+	v, _ := r.ReadBool()
+	r.Align()
+	u3, _ := r.ReadUnsigned(8)
+	if u1 != 4 || u2 != 5 || i != -2 || v || u3 != 0x1F || r.Pos() != 24 {
+		t.Errorf("Unexpected values: %v %v %v %v %v (position %v)", u1, u2, i, v, u3, r.Pos())
+	}
+}
+
+// Write random values at random widths, and
+// check that they agree with PutBits, and
+// that they read back correctly.
+func TestBitWriterRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(6102))
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		for i := 0; i < 1000; i++ {
+			var widths []int
+			var vals []uint64
+			total := 0
+			for total < 300 {
+				width := 1 + r.Intn(64)
+				u := r.Uint64()
+				if width < 64 {
+					u &= uint64(1)<<uint(width) - 1
+				}
+				widths = append(widths, width)
+				vals = append(vals, u)
+				total += width
+			}
+
+			// Start with random bytes to check
+			// that writes replace them
+			b := make([]byte, bitsToBytes(uint64(total)))
+			r.Read(b)
+			expect := make([]byte, len(b))
+			copy(expect, b)
+			w := NewBitWriter(b, order)
+			off := 0
+			for j, width := range widths {
+				if err := w.WriteUnsigned(vals[j], width); err != nil {
+					t.Fatalf("%v: Unexpected error: %v", order, err)
+				}
+				clearBits(order, expect, uint64(off), uint64(width))
+				order.PutBits(expect, off, width, vals[j])
+				off += width
+			}
+			if !bytes.Equal(b, expect) {
+				t.Fatalf("%v: Expected %#v; got %#v", order, expect, b)
+			}
+
+			rd := NewBitReader(b, order)
+			for j, width := range widths {
+				u, err := rd.ReadUnsigned(width)
+				if err != nil {
+					t.Fatalf("%v: Unexpected error: %v", order, err)
+				}
+				if u != vals[j] {
+					t.Fatalf("%v: Expected %#x; got %#x (width %v)", order, vals[j], u, width)
+				}
+			}
+		}
+	}
+}
+
+func TestBitWriterSigned(t *testing.T) {
+	b := make([]byte, 9)
+	for width := 1; width <= 64; width++ {
+		for _, i := range []int64{-1 << uint(width-1), -1, 0, 1<<uint(width-1) - 1} {
+			w := NewBitWriter(b, LSBFirst)
+			w.WriteUnsigned(0, 3)
+			if err := w.WriteSigned(i, width); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			r := NewBitReader(b, LSBFirst)
+			r.Skip(3)
+			if got, _ := r.ReadSigned(width); got != i {
+				t.Errorf("Expected %v; got %v (width %v)", i, got, width)
+			}
+		}
+	}
+}
+
+func TestBitWriterErrors(t *testing.T) {
+	b := make([]byte, 2)
+	w := NewBitWriter(b, LSBFirst)
+	w.WriteUnsigned(1, 4)
+	testBitError(t, Overflow, "gopack: value out of range: max 7; got 8", 4, w.WriteUnsigned(8, 3))
+	testBitError(t, Overflow, "gopack: value out of range: max 3, min -4; got -5", 4, w.WriteSigned(-5, 3))
+	testBitError(t, BadWidth, "gopack: bad bit width (0)", 4, w.WriteUnsigned(0, 0))
+	testBitError(t, BadWidth, "gopack: bad bit width (65)", 4, w.WriteSigned(0, 65))
+	testBitError(t, BadWidth, "gopack: negative skip (-1)", 4, w.Skip(-1))
+	testBitError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", 4, w.WriteUnsigned(0, 13))
+	testBitError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", 4, w.Skip(13))
+	if w.Pos() != 4 {
+		t.Errorf("Expected position 4; got %v", w.Pos())
+	}
+	if err := w.Skip(12); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	testBitError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", 16, w.WriteBool(true))
+
+	r := NewBitReader(b, LSBFirst)
+	r.Skip(9)
+	_, err := r.ReadUnsigned(8)
+	testBitError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", 9, err)
+	_, err = r.ReadSigned(-1)
+	testBitError(t, BadWidth, "gopack: bad bit width (-1)", 9, err)
+	if r.Pos() != 9 {
+		t.Errorf("Expected position 9; got %v", r.Pos())
+	}
+	r.Align()
+	r.Align()
+	if r.Pos() != 16 {
+		t.Errorf("Expected position 16; got %v", r.Pos())
+	}
+}
+
+func testBitError(t *testing.T, kind ErrorKind, msg string, offset uint64, err error) {
+	e, ok := err.(Error)
+	if !ok {
+		t.Errorf("Expected error of type Error; got %v", err)
+		return
+	}
+	if e.Kind != kind || e.Error() != msg || e.Offset != offset {
+		t.Errorf("Expected %v error %q at offset %v; got %v error %q at offset %v", kind, msg, offset, e.Kind, e.Error(), e.Offset)
+	}
+}
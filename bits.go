@@ -0,0 +1,181 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+// The functions in this file access bit
+// ranges in a byte slice one byte at a
+// time. Unlike the packers in pack.go,
+// they make no assumptions about the
+// host's byte order or alignment rules.
+//
+// Bit offsets are counted from the start
+// of b. In LSBFirst order, offset 0 is the
+// least significant bit of b[0]; in MSBFirst
+// order, it is the most significant bit.
+
+type putBitsFunc func(b []byte, off uint64, width uint8, u uint64)
+type getBitsFunc func(b []byte, off uint64, width uint8) uint64
+
+func bitsFuncs(order BitOrder) (putBitsFunc, getBitsFunc) {
+	if order == MSBFirst {
+		return putBitsMSB, getBitsMSB
+	}
+	return putBitsLSB, getBitsLSB
+}
+
+// OR u into the width bits of b starting
+// at off, least significant bit first.
+// u must not have any bits set at or
+// above bit width.
+func putBitsLSB(b []byte, off uint64, width uint8, u uint64) {
+	i := off / 8
+	lsb := uint8(off % 8)
+	b[i] |= byte(u << lsb)
+	u >>= 8 - lsb
+	for n := int(lsb) + int(width) - 8; n > 0; n -= 8 {
+		i++
+		b[i] |= byte(u)
+		u >>= 8
+	}
+}
+
+// Get the width bits of b starting at
+// off, least significant bit first.
+func getBitsLSB(b []byte, off uint64, width uint8) uint64 {
+	i := off / 8
+	lsb := uint8(off % 8)
+	u := uint64(b[i]) >> lsb
+	for got := 8 - lsb; got < width; got += 8 {
+		i++
+		u |= uint64(b[i]) << got
+	}
+	if width < 64 {
+		u &= (uint64(1) << width) - 1
+	}
+	return u
+}
+
+// OR u into the width bits of b starting
+// at off, most significant bit first.
+// u must not have any bits set at or
+// above bit width.
+func putBitsMSB(b []byte, off uint64, width uint8, u uint64) {
+	end := off + uint64(width)
+	i := (end - 1) / 8
+	// Number of unused bits at the
+	// bottom of the last byte
+	pad := uint8((8 - end%8) % 8)
+	b[i] |= byte(u << pad)
+	u >>= 8 - pad
+	for n := int(width) + int(pad) - 8; n > 0; n -= 8 {
+		i--
+		b[i] |= byte(u)
+		u >>= 8
+	}
+}
+
+// Get the width bits of b starting at
+// off, most significant bit first.
+func getBitsMSB(b []byte, off uint64, width uint8) uint64 {
+	end := off + uint64(width)
+	i := (end - 1) / 8
+	pad := uint8((8 - end%8) % 8)
+	u := uint64(b[i]) >> pad
+	for got := 8 - pad; got < width; got += 8 {
+		i--
+		u |= uint64(b[i]) << got
+	}
+	if width < 64 {
+		u &= (uint64(1) << width) - 1
+	}
+	return u
+}
+
+// Zero the width bits of b starting
+// at off, laid out in order.
+func clearBits(order BitOrder, b []byte, off, width uint64) {
+	for width > 0 {
+		i := off / 8
+		bit := off % 8
+		n := 8 - bit
+		if n > width {
+			n = width
+		}
+		m := byte(1)<<n - 1
+		if order == MSBFirst {
+			m <<= 8 - bit - n
+		} else {
+			m <<= bit
+		}
+		b[i] &^= m
+		off += n
+		width -= n
+	}
+}
+
+// PutBits ORs the low width bits of u into the
+// width bits of b starting at bit offset off,
+// laid out in order o. width must be in the
+// range [1, 64], and b must be long enough to
+// hold the bits. It is intended for use by
+// implementations of BitPacker.
+func (o BitOrder) PutBits(b []byte, off, width int, u uint64) {
+	if width < 64 {
+		u &= (uint64(1) << uint(width)) - 1
+	}
+	put, _ := bitsFuncs(o)
+	put(b, uint64(off), uint8(width), u)
+}
+
+// GetBits returns the width bits of b starting
+// at bit offset off, laid out in order o. width
+// must be in the range [1, 64], and b must be
+// long enough to hold the bits. It is intended
+// for use by implementations of BitUnpacker.
+func (o BitOrder) GetBits(b []byte, off, width int) uint64 {
+	_, get := bitsFuncs(o)
+	return get(b, uint64(off), uint8(width))
+}
+
+// OR the bytes of src into dst shifted by
+// phase bits (in the range [0, 8)), so that
+// bit 0 of src lands at bit offset phase in
+// dst. dst must be long enough to hold the
+// shifted bits which are set in src.
+func shiftInBits(order BitOrder, dst []byte, phase uint8, src []byte) {
+	for i, s := range src {
+		if order == MSBFirst {
+			dst[i] |= s >> phase
+			if phase != 0 && i+1 < len(dst) {
+				dst[i+1] |= s << (8 - phase)
+			}
+		} else {
+			dst[i] |= s << phase
+			if phase != 0 && i+1 < len(dst) {
+				dst[i+1] |= s >> (8 - phase)
+			}
+		}
+	}
+}
+
+// The inverse of shiftInBits: fill dst
+// with the bits of src starting at bit
+// offset phase.
+func shiftOutBits(order BitOrder, dst []byte, phase uint8, src []byte) {
+	for i := range dst {
+		var next byte
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+		switch {
+		case phase == 0:
+			dst[i] = src[i]
+		case order == MSBFirst:
+			dst[i] = src[i]<<phase | next>>(8-phase)
+		default:
+			dst[i] = src[i]>>phase | next<<(8-phase)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// A Codec packs and unpacks values of a single
+// type. Pack and Unpack look up the type of their
+// argument on every call; a Codec does so once,
+// when it is created, and so is faster to use in
+// a loop. Any error in the type is reported when
+// the Codec is created.
+//
+// A Codec may be used by multiple goroutines
+// simultaneously.
+type Codec struct {
+	typ, ptr reflect.Type
+	order    BitOrder
+	packer   packer
+	unpacker unpacker
+	layout   Layout
+	// For variable-size types, returns
+	// the size of a value in bits
+	size func(v reflect.Value) uint64
+	// Whether the type has generated methods
+	// for the Codec's bit order
+	gen bool
+}
+
+// CodecFor returns a Codec for values of type typ,
+// which is subject to the same restrictions as the
+// argument to Pack, and which uses LSBFirst order.
+// If typ is a pointer type, the Codec is for the
+// type it points to. All errors returned are of
+// type Error.
+func CodecFor(typ reflect.Type) (*Codec, error) {
+	return Config{}.CodecFor(typ)
+}
+
+// CodecFor is like the package-level
+// CodecFor, but uses the options in c.
+func (c Config) CodecFor(typ reflect.Type) (*Codec, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	ptr := reflect.PtrTo(typ)
+	p := packerFor(typ, c.Order)
+	if p.err != nil {
+		return nil, p.err
+	}
+	u := unpackerFor(ptr, c.Order)
+	if u.err != nil {
+		return nil, u.err
+	}
+	pl, err := planFor(typ)
+	if err != nil {
+		return nil, err
+	}
+	var layout Layout
+	switch {
+	case pl.varSize:
+	case pl.kind == planStruct:
+		layout = Layout{pl.layout().Children, p.bits, p.bytes}
+	default:
+		layout = Layout{Bits: p.bits, Bytes: p.bytes}
+	}
+	gen := ptr.Implements(packerType) && ptr.Implements(unpackerType) &&
+		generatedFor(reflect.New(typ).Interface().(Packer), ptr, c.Order)
+	return &Codec{
+		typ:      typ,
+		ptr:      ptr,
+		order:    c.Order,
+		packer:   p.packer,
+		unpacker: u.unpacker,
+		layout:   layout,
+		size:     p.size,
+		gen:      gen,
+	}, nil
+}
+
+// Type returns the type for which c was created.
+func (c *Codec) Type() reflect.Type { return c.typ }
+
+// Size returns the number of bytes needed to
+// pack a value, or -1 if the type has
+// variable-length fields, in which case the
+// size depends on the value (see PackedSizeof).
+func (c *Codec) Size() int {
+	if c.size != nil {
+		return -1
+	}
+	return c.layout.Bytes
+}
+
+// Layout returns the layout of the packed type,
+// as computed by LayoutOf. For types which
+// implement BitPacker, Fields is empty, and for
+// types with variable-length fields, Layout
+// returns the zero Layout.
+func (c *Codec) Layout() Layout { return c.layout }
+
+// Pack packs v, which must be a value of the
+// Codec's type or a non-nil pointer to one, into
+// b. It behaves like PackE, except that if v is
+// of any other type, it returns an error of kind
+// UnsupportedType.
+func (c *Codec) Pack(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch reflect.TypeOf(v) {
+	case c.typ:
+	case c.ptr:
+		rv = rv.Elem()
+	default:
+		return c.typeError(reflect.TypeOf(v))
+	}
+	if c.gen {
+		if p, ok := v.(Packer); ok {
+			return p.PackBits(b)
+		}
+	}
+	return c.pack(b, rv)
+}
+
+// Unpack unpacks b into v, which must be a
+// non-nil pointer to a value of the Codec's
+// type. It behaves like UnpackE, except that
+// if v is of any other type, it returns an
+// error of kind UnsupportedType.
+func (c *Codec) Unpack(b []byte, v interface{}) error {
+	if reflect.TypeOf(v) != c.ptr {
+		return c.typeError(reflect.TypeOf(v))
+	}
+	if c.gen {
+		return v.(Unpacker).UnpackBits(b)
+	}
+	return c.unpacker(b, reflect.ValueOf(v))
+}
+
+// Pack v, which is of type c.typ
+func (c *Codec) pack(b []byte, v reflect.Value) error {
+	bytes := c.layout.Bytes
+	if c.size != nil {
+		bytes = bitsToBytes(c.size(v))
+	}
+	if len(b) < bytes {
+		return newError(ShortBuffer, "buffer too small (%v; need %v)", len(b), bytes)
+	}
+	for i := 0; i < bytes; i++ {
+		b[i] = 0
+	}
+	return c.packer(b, v)
+}
+
+func (c *Codec) typeError(typ reflect.Type) error {
+	return newError(UnsupportedType, "value of type %v passed to Codec for type %v", typ, c.typ)
+}
@@ -0,0 +1,64 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// CodecOf is like Codec, but is specific to the
+// type T at compile time, so that values need
+// not be passed as interface{} values.
+type CodecOf[T any] struct {
+	c *Codec
+}
+
+// NewCodec returns a CodecOf for the type T,
+// which uses LSBFirst order. It is like
+// CodecFor(reflect.TypeOf(new(T)).Elem()).
+func NewCodec[T any]() (*CodecOf[T], error) {
+	return NewCodecWith[T](Config{})
+}
+
+// NewCodecWith is like NewCodec, but uses
+// the options in c.
+func NewCodecWith[T any](c Config) (*CodecOf[T], error) {
+	codec, err := c.CodecFor(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return &CodecOf[T]{codec}, nil
+}
+
+// Codec returns the underlying Codec.
+func (c *CodecOf[T]) Codec() *Codec { return c.c }
+
+// Size returns the number of bytes needed to
+// pack a value, as described by Codec.Size.
+func (c *CodecOf[T]) Size() int { return c.c.Size() }
+
+// Layout returns the layout of T, as
+// described by Codec.Layout.
+func (c *CodecOf[T]) Layout() Layout { return c.c.layout }
+
+// Pack packs *v into b. It behaves like PackE.
+func (c *CodecOf[T]) Pack(b []byte, v *T) error {
+	if c.c.gen {
+		return any(v).(Packer).PackBits(b)
+	}
+	return c.c.pack(b, reflect.ValueOf(v).Elem())
+}
+
+// Unpack unpacks b into *v. It behaves
+// like UnpackE.
+func (c *CodecOf[T]) Unpack(b []byte, v *T) error {
+	if c.c.gen {
+		return any(v).(Unpacker).UnpackBits(b)
+	}
+	return c.c.unpacker(b, reflect.ValueOf(v))
+}
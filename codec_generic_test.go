@@ -0,0 +1,52 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package gopack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecOf(t *testing.T) {
+	c, err := NewCodecWith[twelve](Config{Order: MSBFirst})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Size() != 2 || c.Codec().Type() != reflect.TypeOf(twelve{}) || c.Layout().Bits != 12 {
+		t.Errorf("Unexpected size %v, type %v, or layout %+v", c.Size(), c.Codec().Type(), c.Layout())
+	}
+	val := twelve{17, -40}
+	b := make([]byte, 2)
+	if err := c.Pack(b, &val); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var expect [2]byte
+	Config{Order: MSBFirst}.Pack(expect[:], val)
+	if string(b) != string(expect[:]) {
+		t.Errorf("Expected %v; got %v", expect, b)
+	}
+	var val2 twelve
+	if err := c.Unpack(b, &val2); err != nil || val2 != val {
+		t.Errorf("Expected %v; got %v (error %v)", val, val2, err)
+	}
+	testCodecError(t, Overflow, "gopack: B: value out of range: max 63, min -64; got 64", c.Pack(b, &twelve{B: 64}))
+
+	if _, err := NewCodec[int](); err == nil || err.(Error).Kind != NonStruct {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func BenchmarkCodecOfPack(b *testing.B) {
+	c, _ := NewCodec[benchSample]()
+	buf := make([]byte, c.Size())
+	var s benchSample
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Pack(buf, &s)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		cfg := Config{Order: order}
+		c, err := cfg.CodecFor(reflect.TypeOf(&twelve{}))
+		if err != nil {
+			t.Fatalf("%v: Unexpected error: %v", order, err)
+		}
+		if c.Type() != reflect.TypeOf(twelve{}) || c.Size() != 2 {
+			t.Errorf("%v: Unexpected type %v or size %v", order, c.Type(), c.Size())
+		}
+		if l := c.Layout(); !reflect.DeepEqual(l, LayoutOf(twelve{})) {
+			t.Errorf("%v: Expected layout %+v; got %+v", order, LayoutOf(twelve{}), l)
+		}
+
+		val := twelve{17, -40}
+		var expect [2]byte
+		cfg.Pack(expect[:], val)
+		for _, v := range []interface{}{val, &val} {
+			b := [3]byte{0xFF, 0xFF, 0xFF}
+			if err := c.Pack(b[:], v); err != nil {
+				t.Fatalf("%v: Unexpected error: %v", order, err)
+			}
+			if b != [3]byte{expect[0], expect[1], 0xFF} {
+				t.Errorf("%v: Expected %v; got %v", order, expect, b)
+			}
+		}
+		var val2 twelve
+		if err := c.Unpack(expect[:], &val2); err != nil || val2 != val {
+			t.Errorf("%v: Expected %v; got %v (error %v)", order, val, val2, err)
+		}
+	}
+
+	// Types with a custom encoding have no fields
+	c, err := CodecFor(reflect.TypeOf(mac{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if l := c.Layout(); l.Fields != nil || l.Bits != 48 || l.Bytes != 6 {
+		t.Errorf("Unexpected layout %+v", l)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	type bad struct {
+		A uint8 `gopack:"9"`
+	}
+	if _, err := CodecFor(reflect.TypeOf(bad{})); err == nil || err.Error() != "gopack: A: struct tag too wide for type uint8 (9)" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := CodecFor(reflect.TypeOf(0)); err == nil || err.(Error).Kind != NonStruct {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := CodecFor(reflect.TypeOf(packOnly(0))); err == nil || err.(Error).Kind != UnsupportedType {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	c, _ := CodecFor(reflect.TypeOf(twelve{}))
+	testCodecError(t, UnsupportedType, "gopack: value of type gopack.stamp passed to Codec for type gopack.twelve", c.Pack(make([]byte, 2), stamp(0)))
+	testCodecError(t, UnsupportedType, "gopack: value of type <nil> passed to Codec for type gopack.twelve", c.Pack(make([]byte, 2), nil))
+	testCodecError(t, UnsupportedType, "gopack: value of type gopack.twelve passed to Codec for type gopack.twelve", c.Unpack(make([]byte, 2), twelve{}))
+	testCodecError(t, ShortBuffer, "gopack: buffer too small (1; need 2)", c.Pack(make([]byte, 1), twelve{}))
+	testCodecError(t, ShortBuffer, "gopack: buffer too small (1; need 2)", c.Unpack(make([]byte, 1), &twelve{}))
+	testCodecError(t, Overflow, "gopack: A: value out of range: max 31; got 32", c.Pack(make([]byte, 2), twelve{A: 32}))
+}
+
+func testCodecError(t *testing.T, kind ErrorKind, msg string, err error) {
+	e, ok := err.(Error)
+	if !ok || e.Kind != kind || e.Error() != msg {
+		t.Errorf("Expected error \"%v\" (%v); got %v", msg, kind, err)
+	}
+}
+
+func BenchmarkCodecPack(b *testing.B) {
+	c, _ := CodecFor(reflect.TypeOf(benchSample{}))
+	buf := make([]byte, c.Size())
+	var s benchSample
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Pack(buf, &s)
+	}
+}
+
+func BenchmarkCodecUnpack(b *testing.B) {
+	c, _ := CodecFor(reflect.TypeOf(benchSample{}))
+	buf := make([]byte, c.Size())
+	var s benchSample
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Unpack(buf, &s)
+	}
+}
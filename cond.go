@@ -0,0 +1,54 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// Reports whether a field with the given
+// tag has an "if" option, which makes it
+// conditional on an earlier bool field.
+func isConditional(tag reflect.StructTag) bool {
+	t, _ := parseTag("", 0, tag)
+	return t.has("if")
+}
+
+// A conditional field is packed only if the
+// bool field named by its "if" option is set.
+// Otherwise, it takes up no space, and Unpack
+// sets it to its zero value. Like a slice,
+// it is variable-size, and is resolved
+// against its siblings by its struct.
+func makeCondPlan(name, path string, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	t, err := parseTag(path, 0, tag)
+	if err != nil {
+		return nil, err
+	}
+	p := &plan{kind: planCond, name: name, path: path, typ: typ, varSize: true, lenField: -1, condName: t.opts["if"]}
+	if p.condName == "" {
+		return nil, newFieldError(BadTag, path, 0, 0, "bad struct tag: option \"if\" needs a field name")
+	}
+	// The rest of the tag applies to the field
+	p.elem, err = makeMemberPlan(name, path, 0, typ, withoutOptions(tag, "if"))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Resolves the if option of f, which is
+// field i of p, to the index of the field
+// holding its condition, along with any
+// options of the field itself.
+func (p *plan) resolveCond(f *plan, i int) error {
+	for j, g := range p.fields[:i] {
+		if g != nil && g.name == f.condName && g.kind == planBool {
+			f.condField = j
+			return p.resolveField(f.elem, i)
+		}
+	}
+	return newFieldError(BadTag, f.path, 0, 0, "bad struct tag: if %q is not an earlier bool field", f.condName)
+}
@@ -0,0 +1,94 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"testing"
+)
+
+type condHeader struct {
+	HasExt  bool
+	HasOpts bool
+	Len     uint8    `gopack:"4"`
+	Ext     ptrExt   `gopack:"if=HasExt"`
+	Opts    []uint8  `gopack:"5,if=HasOpts,lenfield=Len"`
+	ID      uint16   `gopack:"10,if=HasExt"`
+	Tail    [2]uint8 `gopack:"3"`
+}
+
+func TestCondFields(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		v := condHeader{true, true, 2, ptrExt{21, true}, []uint8{3, 31}, 0x3FF, [2]uint8{5, 6}}
+		expect := make([]byte, 5)
+		w := NewBitWriter(expect, order)
+		w.WriteBool(true)
+		w.WriteBool(true)
+		w.WriteUnsigned(2, 4)
+		w.WriteUnsigned(21, 5)
+		w.WriteBool(true)
+		w.WriteUnsigned(3, 5)
+		w.WriteUnsigned(31, 5)
+		w.WriteUnsigned(0x3FF, 10)
+		w.WriteUnsigned(5, 3)
+		w.WriteUnsigned(6, 3)
+		testVarLen(t, c, v, &condHeader{}, expect[:bitsToBytes(uint64(w.Pos()))])
+
+		v = condHeader{Tail: [2]uint8{5, 6}}
+		expect = make([]byte, 2)
+		w = NewBitWriter(expect, order)
+		w.WriteUnsigned(0, 6)
+		w.WriteUnsigned(5, 3)
+		w.WriteUnsigned(6, 3)
+		testVarLen(t, c, v, &condHeader{}, expect)
+	}
+
+	// Fields whose condition is not set are
+	// ignored by Pack and cleared by Unpack
+	v := condHeader{HasOpts: true, Ext: ptrExt{A: 1}, Opts: []uint8{}, ID: 7}
+	b := AppendPack(nil, v)
+	got := condHeader{Ext: ptrExt{A: 2}, ID: 8}
+	Unpack(b, &got)
+	if !reflect.DeepEqual(got, condHeader{HasOpts: true}) {
+		t.Errorf("Unexpected value %+v", got)
+	}
+}
+
+func TestCondErrors(t *testing.T) {
+	testError(t, BadLength, "gopack: Opts: length field Len is 1, but length is 0", func() {
+		Pack(make([]byte, 4), condHeader{HasOpts: true, Len: 1})
+	})
+	testError(t, BadTag, `gopack: B: bad struct tag: if "A" is not an earlier bool field`, func() {
+		Pack(nil, struct {
+			A uint8
+			B uint8 `gopack:"if=A"`
+		}{})
+	})
+	testError(t, BadTag, `gopack: B: bad struct tag: if "A" is not an earlier bool field`, func() {
+		Pack(nil, struct {
+			B uint8 `gopack:"if=A"`
+			A bool
+		}{})
+	})
+	testError(t, BadTag, "gopack: B: bad struct tag: option \"if\" needs a field name", func() {
+		Pack(nil, struct {
+			A bool
+			B uint8 `gopack:"4,if"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: B: bad struct tag: offset on variable-size field", func() {
+		Pack(nil, struct {
+			A bool
+			B uint8 `gopack:"if=A,offset=4"`
+		}{})
+	})
+	testError(t, TagTooWide, "gopack: B: struct tag too wide for type uint8 (9)", func() {
+		Pack(nil, struct {
+			A bool
+			B uint8 `gopack:"9,if=A"`
+		}{})
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// BitOrder specifies how fields are laid
+// out in packed data.
+type BitOrder int
+
+const (
+	// LSBFirst packs the first field into the
+	// least significant bits of the first byte,
+	// and stores the bits of each field least
+	// significant bit first, so that multi-byte
+	// fields appear in little-endian byte order.
+	// This is the default.
+	LSBFirst BitOrder = iota
+
+	// MSBFirst packs the first field into the
+	// most significant bits of the first byte,
+	// and stores the bits of each field most
+	// significant bit first, so that multi-byte
+	// fields appear in big-endian byte order.
+	// This is the layout used by most network
+	// protocol headers.
+	//
+	//	type ipv4Prefix struct {
+	//		Version, IHL uint8 `gopack:"4"`
+	//	}
+	//
+	//	// b[0] == 0x45
+	//	Config{Order: MSBFirst}.Pack(b, ipv4Prefix{4, 5})
+	MSBFirst
+)
+
+func (o BitOrder) String() string {
+	switch o {
+	case LSBFirst:
+		return "LSBFirst"
+	case MSBFirst:
+		return "MSBFirst"
+	}
+	return "BitOrder(" + strconv.Itoa(int(o)) + ")"
+}
+
+// A Config specifies options for packing and
+// unpacking. The zero Config uses LSBFirst
+// order, and is what the package-level
+// functions such as Pack and Unpack use.
+type Config struct {
+	Order BitOrder
+
+	// Contiguous applies to sequences of values
+	// (see Encoder, Decoder, and PackSlice). If
+	// it is set, each value starts at the bit
+	// following the end of the last, rather than
+	// at the next byte boundary, so that ten
+	// 12-bit values take up 15 bytes rather
+	// than 20.
+	Contiguous bool
+}
+
+// Pack is like the package-level Pack,
+// but uses the options in c.
+func (c Config) Pack(b []byte, strct interface{}) {
+	if err := c.PackE(b, strct); err != nil {
+		panic(err)
+	}
+}
+
+// PackE is like the package-level PackE,
+// but uses the options in c.
+func (c Config) PackE(b []byte, strct interface{}) error {
+	return packE(c.Order, b, strct)
+}
+
+// AppendPack is like the package-level
+// AppendPack, but uses the options in c.
+func (c Config) AppendPack(dst []byte, strct interface{}) []byte {
+	b, err := c.AppendPackE(dst, strct)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// AppendPackE is like the package-level
+// AppendPackE, but uses the options in c.
+func (c Config) AppendPackE(dst []byte, strct interface{}) ([]byte, error) {
+	return appendPackE(c.Order, dst, strct)
+}
+
+// Unpack is like the package-level Unpack,
+// but uses the options in c.
+func (c Config) Unpack(b []byte, strct interface{}) {
+	if err := c.UnpackE(b, strct); err != nil {
+		panic(err)
+	}
+}
+
+// UnpackE is like the package-level UnpackE,
+// but uses the options in c.
+func (c Config) UnpackE(b []byte, strct interface{}) error {
+	return unpackE(c.Order, b, strct)
+}
+
+// PackedSizeof is like the package-level
+// PackedSizeof, but uses the options in c.
+func (c Config) PackedSizeof(strct interface{}) int {
+	p := packerFor(reflect.TypeOf(strct), c.Order)
+	if p.err != nil {
+		panic(p.err)
+	}
+	_, bytes := p.sizeof(reflect.ValueOf(strct))
+	return bytes
+}
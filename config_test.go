@@ -0,0 +1,103 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMSBFirstIPv4(t *testing.T) {
+	type ipv4Header struct {
+		Version, IHL uint8 `gopack:"4"`
+		DSCP         uint8 `gopack:"6"`
+		ECN          uint8 `gopack:"2"`
+		TotalLength  uint16
+		ID           uint16
+		Flags        uint8  `gopack:"3"`
+		FragOffset   uint16 `gopack:"13"`
+	}
+
+	c := Config{Order: MSBFirst}
+	val := ipv4Header{4, 5, 46, 1, 0x1234, 0xBEEF, 2, 0x1ABC}
+	b := make([]byte, 8)
+	c.Pack(b, val)
+	expect := []byte{0x45, 0xB9, 0x12, 0x34, 0xBE, 0xEF, 0x5A, 0xBC}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %#v; got %#v", expect, b)
+	}
+
+	var val2 ipv4Header
+	c.Unpack(b, &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+}
+
+func TestMSBFirstDNSFlags(t *testing.T) {
+	type dnsFlags struct {
+		QR             bool
+		Opcode         uint8 `gopack:"4"`
+		AA, TC, RD, RA bool
+		Z              uint8 `gopack:"3"`
+		RCode          int8  `gopack:"4"`
+	}
+
+	c := Config{Order: MSBFirst}
+	val := dnsFlags{true, 2, false, true, true, false, 0, -3}
+	b := make([]byte, 2)
+	c.Pack(b, &val)
+	// 1 0010 0 1 1 0 000 1101
+	expect := []byte{0x93, 0x0D}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %#v; got %#v", expect, b)
+	}
+
+	var val2 dnsFlags
+	c.Unpack(b, &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+}
+
+func TestCoverMSBFirst(t *testing.T) {
+	rand.Seed(7102)
+	testCoverOrder(t, MSBFirst, struct {
+		F1 uint8 `gopack:"3"`
+		F2 uint16
+		F3 int32 `gopack:"21"`
+		F4 bool
+	}{})
+	testCoverOrder(t, MSBFirst, struct {
+		F1 uint8 `gopack:"7"`
+		F2 uint64
+		F3 int64 `gopack:"63"`
+	}{})
+	testCoverOrder(t, MSBFirst, struct {
+		F1 bool
+		F2 struct {
+			F1 int8 `gopack:"5"`
+			F2 uint32
+		}
+		F3 uint64 `gopack:"40"`
+	}{})
+}
+
+func TestConfigErrors(t *testing.T) {
+	type typ struct {
+		F1 uint8 `gopack:"4"`
+	}
+
+	c := Config{Order: MSBFirst}
+	testError(t, Overflow, "gopack: F1: value out of range: max 15; got 16", func() {
+		c.Pack(make([]byte, 1), typ{16})
+	})
+	testError(t, ShortBuffer, "gopack: buffer too small (0; need 1)", func() {
+		c.Unpack(nil, &typ{})
+	})
+	if sz := c.PackedSizeof(typ{}); sz != 1 {
+		t.Errorf("Expected a packed size of 1 but got %d", sz)
+	}
+}
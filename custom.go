@@ -0,0 +1,165 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"errors"
+	"reflect"
+)
+
+// BitPacker is implemented by types which control
+// their own packed encoding. A field whose type (or
+// a pointer to whose type) implements BitPacker is
+// packed by calling PackBitsAt rather than according
+// to its kind, as is a BitPacker passed to Pack.
+//
+// BitWidth returns the number of bits the encoding
+// occupies. It is called once, on the zero value,
+// and so must not depend on the value.
+//
+// PackBitsAt packs the value into the BitWidth bits
+// of dst starting at bitOffset, laid out in order.
+// Those bits are zero when PackBitsAt is called, and
+// it must not modify any others. BitOrder's PutBits
+// method is a convenient way to do so.
+type BitPacker interface {
+	BitWidth() int
+	PackBitsAt(dst []byte, bitOffset int, order BitOrder) error
+}
+
+// BitUnpacker is the counterpart of BitPacker.
+// UnpackBitsAt unpacks the value from the BitWidth
+// bits of src starting at bitOffset, laid out in
+// order. A type which implements one of BitPacker
+// and BitUnpacker must implement the other as well.
+//
+// If PackBitsAt or UnpackBitsAt returns an error,
+// Pack or Unpack report it as an Error with the
+// path of the field at fault. Its Kind is that of
+// any Error or ErrorKind which the error wraps, or
+// else CustomEncoding.
+type BitUnpacker interface {
+	BitWidth() int
+	UnpackBitsAt(src []byte, bitOffset int, order BitOrder) error
+}
+
+var (
+	bitPackerType   = reflect.TypeOf((*BitPacker)(nil)).Elem()
+	bitUnpackerType = reflect.TypeOf((*BitUnpacker)(nil)).Elem()
+)
+
+// Reports whether typ or *typ implements
+// BitPacker or BitUnpacker. Interface types
+// which embed them are not custom, since
+// their zero value has no BitWidth to call.
+func isCustom(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Interface {
+		return false
+	}
+	ptr := reflect.PtrTo(typ)
+	return typ.Implements(bitPackerType) || ptr.Implements(bitPackerType) ||
+		typ.Implements(bitUnpackerType) || ptr.Implements(bitUnpackerType)
+}
+
+// Returns the value of BitWidth for typ, which
+// must implement iface or have a pointer type
+// which does.
+func getCustomWidth(path string, lsb uint64, typ, iface reflect.Type, tag reflect.StructTag) (uint64, error) {
+	t, err := parseTag(path, lsb, tag)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.allow(path, lsb); err != nil {
+		return 0, err
+	}
+	if t.width != "" {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: width of type %v is given by its BitWidth method", typ)
+	}
+
+	if err := checkCustom(path, lsb, typ, iface); err != nil {
+		return 0, err
+	}
+	var width int
+	if typ.Implements(iface) {
+		width = reflect.Zero(typ).Interface().(interface{ BitWidth() int }).BitWidth()
+	} else {
+		width = reflect.New(typ).Interface().(interface{ BitWidth() int }).BitWidth()
+	}
+	if width < 1 {
+		return 0, newFieldError(UnsupportedType, path, lsb, 0, "bad bit width for type %v (%d)", typ, width)
+	}
+	return uint64(width), nil
+}
+
+// Returns an error if neither typ nor
+// *typ implements iface.
+func checkCustom(path string, lsb uint64, typ, iface reflect.Type) error {
+	if typ.Implements(iface) || reflect.PtrTo(typ).Implements(iface) {
+		return nil
+	}
+	other := bitPackerType
+	if iface == bitPackerType {
+		other = bitUnpackerType
+	}
+	return newFieldError(UnsupportedType, path, lsb, 0, "type %v implements %v but not %v", typ, other, iface)
+}
+
+func makeCustomPacker(order BitOrder, path string, lsb uint64, typ reflect.Type, width uint64) (packer, error) {
+	if err := checkCustom(path, lsb, typ, bitPackerType); err != nil {
+		return nil, err
+	}
+	off := int(lsb)
+	if typ.Implements(bitPackerType) {
+		return func(b []byte, field reflect.Value) error {
+			err := field.Interface().(BitPacker).PackBitsAt(b, off, order)
+			return customError(path, lsb, width, err)
+		}, nil
+	}
+	return func(b []byte, field reflect.Value) error {
+		if !field.CanAddr() {
+			// The struct was passed by value,
+			// so call the method on a copy
+			v := reflect.New(typ)
+			v.Elem().Set(field)
+			field = v.Elem()
+		}
+		err := field.Addr().Interface().(BitPacker).PackBitsAt(b, off, order)
+		return customError(path, lsb, width, err)
+	}, nil
+}
+
+func makeCustomUnpacker(order BitOrder, path string, lsb uint64, typ reflect.Type, width uint64) (unpacker, error) {
+	if err := checkCustom(path, lsb, typ, bitUnpackerType); err != nil {
+		return nil, err
+	}
+	off := int(lsb)
+	if typ.Implements(bitUnpackerType) {
+		return func(b []byte, field reflect.Value) error {
+			err := field.Interface().(BitUnpacker).UnpackBitsAt(b, off, order)
+			return customError(path, lsb, width, err)
+		}, nil
+	}
+	return func(b []byte, field reflect.Value) error {
+		err := field.Addr().Interface().(BitUnpacker).UnpackBitsAt(b, off, order)
+		return customError(path, lsb, width, err)
+	}, nil
+}
+
+// Returns err, returned from a BitPacker or
+// BitUnpacker, as an Error.
+func customError(path string, lsb, width uint64, err error) error {
+	if err == nil {
+		return nil
+	}
+	kind := CustomEncoding
+	var e Error
+	var k ErrorKind
+	if errors.As(err, &e) {
+		kind = e.Kind
+	} else if errors.As(err, &k) {
+		kind = k
+	}
+	return Error{Kind: kind, Path: path, Offset: lsb, Width: width, Err: err}
+}
@@ -0,0 +1,236 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Packed the same way as [6]uint8
+type mac [6]byte
+
+func (mac) BitWidth() int { return 48 }
+
+func (m mac) PackBitsAt(dst []byte, bitOffset int, order BitOrder) error {
+	for i, b := range m {
+		order.PutBits(dst, bitOffset+8*i, 8, uint64(b))
+	}
+	return nil
+}
+
+func (m *mac) UnpackBitsAt(src []byte, bitOffset int, order BitOrder) error {
+	for i := range m {
+		m[i] = byte(order.GetBits(src, bitOffset+8*i, 8))
+	}
+	return nil
+}
+
+// Minutes since the Unix epoch, packed as
+// 20 bits of minutes since stampEpoch
+type stamp int64
+
+const stampEpoch = 26297280 // 2020-01-01
+
+func (stamp) BitWidth() int { return 20 }
+
+func (s stamp) PackBitsAt(dst []byte, bitOffset int, order BitOrder) error {
+	if s < stampEpoch || s >= stampEpoch+1<<20 {
+		return fmt.Errorf("time out of range: %w", Overflow)
+	}
+	order.PutBits(dst, bitOffset, 20, uint64(s-stampEpoch))
+	return nil
+}
+
+func (s *stamp) UnpackBitsAt(src []byte, bitOffset int, order BitOrder) error {
+	*s = stamp(order.GetBits(src, bitOffset, 20)) + stampEpoch
+	return nil
+}
+
+// Implements both interfaces with
+// pointer receivers only
+type ptrOnly struct {
+	V uint8
+}
+
+func (*ptrOnly) BitWidth() int { return 5 }
+
+func (p *ptrOnly) PackBitsAt(dst []byte, bitOffset int, order BitOrder) error {
+	if p.V == 0 {
+		return errors.New("zero value")
+	}
+	order.PutBits(dst, bitOffset, 5, uint64(p.V))
+	return nil
+}
+
+func (p *ptrOnly) UnpackBitsAt(src []byte, bitOffset int, order BitOrder) error {
+	p.V = uint8(order.GetBits(src, bitOffset, 5))
+	return nil
+}
+
+func TestCustomFields(t *testing.T) {
+	type record struct {
+		Flag  bool
+		MAC   mac
+		When  stamp
+		P     ptrOnly
+		Level uint8 `gopack:"3"`
+	}
+	type plain struct {
+		Flag  bool
+		MAC   [6]uint8
+		When  uint32 `gopack:"20"`
+		P     uint8  `gopack:"5"`
+		Level uint8  `gopack:"3"`
+	}
+
+	val := record{true, mac{0x00, 0x1A, 0x2B, 0x3C, 0x4D, 0x5E}, stampEpoch + 12345, ptrOnly{17}, 5}
+	same := plain{true, val.MAC, 12345, 17, 5}
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		if sz := c.PackedSizeof(val); sz != 10 {
+			t.Errorf("%v: Expected a packed size of 10 but got %d", order, sz)
+		}
+
+		var b, expect [10]byte
+		c.Pack(b[:], val)
+		c.Pack(expect[:], same)
+		if b != expect {
+			t.Errorf("%v: Expected %v; got %v", order, expect, b)
+		}
+		// Pack via a pointer so that ptrOnly
+		// is addressable
+		b = [10]byte{}
+		c.Pack(b[:], &val)
+		if b != expect {
+			t.Errorf("%v: Expected %v; got %v", order, expect, b)
+		}
+
+		var val2 record
+		c.Unpack(b[:], &val2)
+		if val2 != val {
+			t.Errorf("%v: Expected %v; got %v", order, val, val2)
+		}
+	}
+
+	l := LayoutOf(record{})
+	if f := l.Fields[2]; f.BitOffset != 49 || f.BitWidth != 20 || f.Children != nil {
+		t.Errorf("Unexpected layout for field When: %+v", f)
+	}
+}
+
+func TestCustomTopLevel(t *testing.T) {
+	m := mac{1, 2, 3, 4, 5, 6}
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		var b [6]byte
+		c.Pack(b[:], m)
+		if b != m {
+			t.Errorf("%v: Expected %v; got %v", order, m, b)
+		}
+		var m2 mac
+		c.Unpack(b[:], &m2)
+		if m2 != m {
+			t.Errorf("%v: Expected %v; got %v", order, m, m2)
+		}
+	}
+
+	var b [1]byte
+	Pack(b[:], ptrOnly{9})
+	if b[0] != 9 {
+		t.Errorf("Expected 9; got %v", b[0])
+	}
+	if sz := PackedSizeof(&ptrOnly{}); sz != 1 {
+		t.Errorf("Expected a packed size of 1 but got %d", sz)
+	}
+}
+
+type packOnly uint8
+
+func (packOnly) BitWidth() int                                    { return 3 }
+func (packOnly) PackBitsAt(dst []byte, off int, o BitOrder) error { return nil }
+
+type zeroWidth uint8
+
+func (zeroWidth) BitWidth() int                                       { return 0 }
+func (zeroWidth) PackBitsAt(dst []byte, off int, o BitOrder) error    { return nil }
+func (*zeroWidth) UnpackBitsAt(src []byte, off int, o BitOrder) error { return nil }
+
+// Returns an Error with only a Kind
+type kindOnly uint8
+
+func (kindOnly) BitWidth() int                                       { return 2 }
+func (kindOnly) PackBitsAt(dst []byte, off int, o BitOrder) error    { return Error{Kind: Overflow} }
+func (*kindOnly) UnpackBitsAt(src []byte, off int, o BitOrder) error { return nil }
+
+func TestCustomErrors(t *testing.T) {
+	type typ struct {
+		F1 uint8 `gopack:"4"`
+		F2 stamp
+	}
+	testError(t, Overflow, "gopack: F2: time out of range: gopack: overflow", func() {
+		Pack(make([]byte, 3), typ{})
+	})
+	err := PackE(make([]byte, 3), typ{})
+	if e := err.(Error); e.Offset != 4 || e.Width != 20 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", e)
+	}
+
+	type typ1 struct {
+		P ptrOnly
+	}
+	testError(t, CustomEncoding, "gopack: P: zero value", func() {
+		Pack(make([]byte, 1), typ1{})
+	})
+
+	type typ2 struct {
+		F packOnly
+	}
+	Pack(make([]byte, 1), typ2{})
+	testError(t, UnsupportedType, "gopack: F: type gopack.packOnly implements gopack.BitPacker but not gopack.BitUnpacker", func() {
+		Unpack(make([]byte, 1), &typ2{})
+	})
+
+	type typ3 struct {
+		F zeroWidth
+	}
+	testError(t, UnsupportedType, "gopack: F: bad bit width for type gopack.zeroWidth (0)", func() {
+		Pack(nil, typ3{})
+	})
+
+	type typ4 struct {
+		F stamp `gopack:"20"`
+	}
+	testError(t, BadTag, "gopack: F: bad struct tag: width of type gopack.stamp is given by its BitWidth method", func() {
+		Pack(nil, typ4{})
+	})
+
+	type typ6 struct {
+		F1 bool
+		F2 kindOnly
+	}
+	testError(t, Overflow, "gopack: F2: gopack: overflow", func() {
+		Pack(make([]byte, 1), typ6{})
+	})
+
+	// Interface types are not custom, even
+	// if they embed BitPacker and BitUnpacker
+	type typ5 struct {
+		F customIface
+	}
+	testError(t, BadTag, "gopack: F: bad struct tag: interface field needs a switch option", func() {
+		Pack(nil, typ5{})
+	})
+	var c customIface = &mac{}
+	testError(t, NonStruct, "gopack: non-struct type gopack.customIface", func() {
+		Unpack(nil, &c)
+	})
+}
+
+type customIface interface {
+	BitPacker
+	BitUnpacker
+}
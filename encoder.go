@@ -0,0 +1,324 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// The size at which an Encoder writes its buffered
+// data, and the size of a Decoder's read buffer
+const streamBufSize = 4096
+
+// The most that a Decoder will buffer to
+// unpack a single variable-size value, so
+// that a corrupt length cannot make it read
+// the rest of the stream into memory
+const maxDecodeSize = 16 << 20
+
+// An Encoder packs a stream of values to an
+// io.Writer. Values are buffered internally,
+// so Flush must be called once all values have
+// been encoded.
+type Encoder struct {
+	w          io.Writer
+	order      BitOrder
+	contiguous bool
+	buf        []byte
+	// The number of bits used in the last
+	// byte of buf, or 0 if it is full
+	phase   uint8
+	scratch []byte
+	err     error
+}
+
+// NewEncoder returns an Encoder which writes
+// to w in LSBFirst order, with each value
+// starting on a byte boundary. To use other
+// options, see Config.
+func NewEncoder(w io.Writer) *Encoder {
+	return Config{}.NewEncoder(w)
+}
+
+// NewEncoder is like the package-level
+// NewEncoder, but uses the options in c.
+func (c Config) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, order: c.Order, contiguous: c.Contiguous}
+}
+
+// Encode packs v, which is subject to the same
+// restrictions as the argument to Pack, and
+// appends it to the stream. If v cannot be
+// packed, Encode returns an error of type
+// Error and nothing is appended. Otherwise,
+// the only errors are those returned from
+// the underlying io.Writer, after which all
+// calls to Encode and Flush fail.
+func (e *Encoder) Encode(v interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	c := packerFor(reflect.TypeOf(v), e.order)
+	if c.err != nil {
+		return c.err
+	}
+
+	bits, bytes := c.sizeof(reflect.ValueOf(v))
+	n := len(e.buf)
+	if e.phase == 0 {
+		e.buf = growZero(e.buf, bytes)
+		if err := packCached(e.order, c, e.buf[n:], v); err != nil {
+			e.buf = e.buf[:n]
+			return err
+		}
+	} else {
+		// Pack into scratch, and then shift the
+		// bits into place after the last record
+		e.scratch = growZero(e.scratch[:0], bytes)
+		if err := packCached(e.order, c, e.scratch, v); err != nil {
+			return err
+		}
+		e.buf = growZero(e.buf, bitsToBytes(uint64(e.phase)+bits)-1)
+		shiftInBits(e.order, e.buf[n-1:], e.phase, e.scratch)
+	}
+	if e.contiguous {
+		e.phase = uint8((uint64(e.phase) + bits) % 8)
+	}
+
+	if len(e.buf) >= streamBufSize {
+		// Hold back any partial byte
+		full := len(e.buf)
+		if e.phase != 0 {
+			full--
+		}
+		if e.write(e.buf[:full]) != nil {
+			return e.err
+		}
+		e.buf = e.buf[:copy(e.buf, e.buf[full:])]
+	}
+	return nil
+}
+
+func (e *Encoder) write(b []byte) error {
+	if _, err := e.w.Write(b); err != nil {
+		e.err = err
+	}
+	return e.err
+}
+
+// Flush writes any buffered data to the
+// underlying io.Writer. In contiguous mode,
+// if the last value ends partway through a
+// byte, the rest of the byte is padded with
+// zeros, and the next value encoded starts
+// on a byte boundary.
+func (e *Encoder) Flush() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.write(e.buf) != nil {
+		return e.err
+	}
+	e.buf = e.buf[:0]
+	e.phase = 0
+	return nil
+}
+
+// A Decoder unpacks a stream of values
+// from an io.Reader. It reads ahead into
+// an internal buffer, and so may read
+// more data than it has decoded.
+type Decoder struct {
+	r          io.Reader
+	order      BitOrder
+	contiguous bool
+	buf        []byte
+	// The unread data is buf[start:], of
+	// which the first phase bits have been
+	// decoded
+	start   int
+	phase   uint8
+	scratch []byte
+	err     error
+	// The error from a variable-size value
+	// which could not be unpacked, after
+	// which the stream cannot be resumed
+	failed error
+}
+
+// NewDecoder returns a Decoder which reads
+// values from r in LSBFirst order, with each
+// value starting on a byte boundary. To use
+// other options, see Config.
+func NewDecoder(r io.Reader) *Decoder {
+	return Config{}.NewDecoder(r)
+}
+
+// NewDecoder is like the package-level
+// NewDecoder, but uses the options in c.
+func (c Config) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, order: c.Order, contiguous: c.Contiguous}
+}
+
+// Decode reads the next value from the stream
+// and unpacks it into v, which is subject to the
+// same restrictions as the argument to Unpack.
+// If the type of v cannot be unpacked, Decode
+// returns an error of type Error and reads
+// nothing. An error unpacking the value itself,
+// such as BadPadding, is also of type Error, but
+// the value is consumed so that decoding may
+// continue with the next one. The exception is
+// a variable-size value, whose end cannot be
+// found if it cannot be unpacked; all later
+// calls to Decode return the same error. This
+// includes a value which would need more than
+// 16MiB of the stream, such as one with a
+// corrupt length, which is reported as a
+// BadLength error.
+//
+// At the end of the stream, Decode returns
+// io.EOF. If the stream ends partway through a
+// value, it returns io.ErrUnexpectedEOF. Other
+// errors are those returned by the io.Reader.
+//
+// In contiguous mode, the padding at the end of
+// the stream is indistinguishable from packed
+// data, so if values may be smaller than 8 bits,
+// the number of values should be recorded
+// separately.
+func (d *Decoder) Decode(v interface{}) error {
+	c := unpackerFor(reflect.TypeOf(v), d.order)
+	if c.err != nil {
+		return c.err
+	}
+	if c.variable {
+		return d.decodeVar(c, v)
+	}
+	need := c.bytes
+	if d.phase != 0 {
+		need = bitsToBytes(uint64(d.phase) + c.bits)
+	}
+	if err := d.fill(need); err != nil {
+		return err
+	}
+
+	b := d.buf[d.start : d.start+need]
+	if d.phase != 0 {
+		d.scratch = growZero(d.scratch[:0], c.bytes)
+		shiftOutBits(d.order, d.scratch, d.phase, b)
+		b = d.scratch
+	}
+	if d.contiguous {
+		bits := uint64(d.phase) + c.bits
+		d.start += int(bits / 8)
+		d.phase = uint8(bits % 8)
+	} else {
+		d.start += need
+	}
+
+	if u, ok := v.(Unpacker); ok && generatedFor(u, reflect.TypeOf(v), d.order) {
+		return u.UnpackBits(b)
+	}
+	return c.unpacker(b, reflect.ValueOf(v))
+}
+
+// Decodes the variable-size value v. Its size
+// is not known until it has been unpacked, so
+// d reads more of the stream and unpacks it
+// again until the buffer holds all of it.
+func (d *Decoder) decodeVar(c cachedUnpacker, v interface{}) error {
+	if d.failed != nil {
+		return d.failed
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	} else {
+		// As with Unpack, the value is
+		// discarded, but is still consumed
+		val = reflect.New(val.Type()).Elem()
+	}
+
+	need := bitsToBytes(uint64(d.phase) + c.bits)
+	for {
+		if err := d.fill(need); err != nil {
+			return err
+		}
+		end, err := c.unpackFrom(d.buf[d.start:], uint64(d.phase), val)
+		if errors.Is(err, ShortBuffer) {
+			// Read as much as the value is known
+			// to need, and at least one more byte
+			have := len(d.buf) - d.start
+			need = have + 1
+			var sb shortBuffer
+			if errors.As(err, &sb) && sb.need > need {
+				need = sb.need
+			}
+			if need > maxDecodeSize {
+				var e Error
+				errors.As(err, &e)
+				d.failed = newFieldError(BadLength, e.Path, e.Offset, e.Width, "value needs more than the maximum of %v bytes", maxDecodeSize)
+				return d.failed
+			}
+			continue
+		}
+		if err != nil {
+			d.failed = err
+			return err
+		}
+		if d.contiguous {
+			d.start += int(end / 8)
+			d.phase = uint8(end % 8)
+		} else {
+			d.start += bitsToBytes(end)
+		}
+		return nil
+	}
+}
+
+// Read until at least n bytes are unread
+func (d *Decoder) fill(n int) error {
+	if len(d.buf)-d.start >= n {
+		return nil
+	}
+	if d.start > 0 {
+		d.buf = d.buf[:copy(d.buf, d.buf[d.start:])]
+		d.start = 0
+	}
+	if cap(d.buf) < n {
+		// Grow geometrically, since decodeVar
+		// asks for one more byte at a time
+		size := streamBufSize
+		if size < 2*cap(d.buf) {
+			size = 2 * cap(d.buf)
+		}
+		if size < n {
+			size = n
+		}
+		buf := make([]byte, len(d.buf), size)
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	for len(d.buf) < n && d.err == nil {
+		m, err := d.r.Read(d.buf[len(d.buf):cap(d.buf)])
+		d.buf = d.buf[:len(d.buf)+m]
+		d.err = err
+	}
+	if len(d.buf) >= n {
+		return nil
+	}
+	if d.err != io.EOF {
+		return d.err
+	}
+	// Any bits left in a partially
+	// decoded byte are padding
+	if len(d.buf) == 0 || (len(d.buf) == 1 && d.phase != 0) {
+		return io.EOF
+	}
+	return io.ErrUnexpectedEOF
+}
@@ -0,0 +1,185 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+type twelve struct {
+	A uint8 `gopack:"5"`
+	B int8  `gopack:"7"`
+}
+
+func TestEncoder(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		for _, contiguous := range []bool{false, true} {
+			c := Config{Order: order, Contiguous: contiguous}
+			var buf bytes.Buffer
+			e := c.NewEncoder(&buf)
+			var vals []twelve
+			for i := 0; i < 10; i++ {
+				v := twelve{uint8(i), int8(-i)}
+				vals = append(vals, v)
+				// Alternate between values and pointers
+				if i%2 == 0 {
+					e.Encode(v)
+				} else {
+					e.Encode(&v)
+				}
+			}
+			if err := e.Flush(); err != nil {
+				t.Fatalf("%v: Unexpected error: %v", c, err)
+			}
+
+			expect := 20
+			if contiguous {
+				expect = 15
+			}
+			if buf.Len() != expect {
+				t.Errorf("%v: Expected %v bytes; got %v", c, expect, buf.Len())
+			}
+			if !contiguous {
+				var b [2]byte
+				c.Pack(b[:], vals[9])
+				if got := buf.Bytes()[18:]; !bytes.Equal(got, b[:]) {
+					t.Errorf("%v: Expected %v; got %v", c, b, got)
+				}
+			}
+
+			// Read a byte at a time to check
+			// values which span reads
+			d := c.NewDecoder(iotest.OneByteReader(&buf))
+			for i, v := range vals {
+				var got twelve
+				if err := d.Decode(&got); err != nil {
+					t.Fatalf("%v: value %v: Unexpected error: %v", c, i, err)
+				}
+				if got != v {
+					t.Errorf("%v: value %v: Expected %v; got %v", c, i, v, got)
+				}
+			}
+			if err := d.Decode(&twelve{}); err != io.EOF {
+				t.Errorf("%v: Expected io.EOF; got %v", c, err)
+			}
+		}
+	}
+}
+
+func TestEncoderContiguous(t *testing.T) {
+	type three struct {
+		A uint8 `gopack:"3"`
+	}
+	c := Config{Order: MSBFirst, Contiguous: true}
+	var buf bytes.Buffer
+	e := c.NewEncoder(&buf)
+	e.Encode(three{5})
+	e.Encode(twelve{0x1F, -1})
+	e.Encode(three{1})
+	e.Flush()
+	// 101 11111 1111111 001 000000
+	expect := []byte{0xBF, 0xFE, 0x40}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("Expected %#v; got %#v", expect, buf.Bytes())
+	}
+
+	// Values after a Flush start
+	// on a byte boundary
+	buf.Reset()
+	e.Encode(three{5})
+	e.Flush()
+	e.Encode(three{5})
+	e.Flush()
+	expect = []byte{0xA0, 0xA0}
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("Expected %#v; got %#v", expect, buf.Bytes())
+	}
+}
+
+// Encode enough values that the
+// Encoder writes before Flush
+func TestEncoderLarge(t *testing.T) {
+	for _, contiguous := range []bool{false, true} {
+		c := Config{Contiguous: contiguous}
+		var buf bytes.Buffer
+		e := c.NewEncoder(&buf)
+		const n = 3 * streamBufSize
+		for i := 0; i < n; i++ {
+			e.Encode(twelve{uint8(i % 32), int8(i % 64)})
+		}
+		if buf.Len() == 0 {
+			t.Errorf("%v: Expected data to be written before Flush", c)
+		}
+		e.Flush()
+
+		d := c.NewDecoder(&buf)
+		for i := 0; i < n; i++ {
+			var got twelve
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("%v: value %v: Unexpected error: %v", c, i, err)
+			}
+			if v := (twelve{uint8(i % 32), int8(i % 64)}); got != v {
+				t.Fatalf("%v: value %v: Expected %v; got %v", c, i, v, got)
+			}
+		}
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(b []byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestEncoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.Encode(twelve{1, 2})
+	err := e.Encode(struct{ A uint8 }{})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	err = e.Encode(twelve{32, 0})
+	if pe, ok := err.(Error); !ok || pe.Kind != Overflow {
+		t.Errorf("Expected an Overflow error; got %v", err)
+	}
+	err = e.Encode(5)
+	if pe, ok := err.(Error); !ok || pe.Kind != NonStruct {
+		t.Errorf("Expected a NonStruct error; got %v", err)
+	}
+	e.Flush()
+	if buf.Len() != 3 {
+		t.Errorf("Expected 3 bytes; got %v", buf.Len())
+	}
+
+	e = NewEncoder(errWriter{})
+	e.Encode(twelve{})
+	if err := e.Flush(); err == nil || err.Error() != "write failed" {
+		t.Errorf("Expected write error; got %v", err)
+	}
+	if err := e.Encode(twelve{}); err == nil || err.Error() != "write failed" {
+		t.Errorf("Expected write error; got %v", err)
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte{1, 2, 3}))
+	var v twelve
+	if err := d.Decode(&v); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF; got %v", err)
+	}
+	if err := d.Decode(new(int)); err == nil {
+		t.Errorf("Expected an error")
+	}
+
+	d = NewDecoder(iotest.TimeoutReader(bytes.NewReader([]byte{1, 2, 3})))
+	d.Decode(&v)
+	if err := d.Decode(&v); err != iotest.ErrTimeout {
+		t.Errorf("Expected iotest.ErrTimeout; got %v", err)
+	}
+}
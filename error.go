@@ -0,0 +1,195 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrorKind describes the category of an Error.
+// ErrorKind implements the error interface so
+// that it may be used as the target of errors.Is:
+//
+//	if errors.Is(err, gopack.Overflow) {
+//		// ...
+//	}
+type ErrorKind int
+
+const (
+	// A struct tag could not be parsed.
+	BadTag ErrorKind = iota + 1
+	// A struct tag specified more bits than
+	// the field's type can hold.
+	TagTooWide
+	// A struct tag specified fewer than 1 bit.
+	TagTooSmall
+	// A value which was not a struct or a pointer
+	// to a struct was passed to be packed or unpacked,
+	// or a value which was not a slice was passed to
+	// PackSlice or UnpackSlice.
+	NonStruct
+	// A field's type cannot be packed, or a
+	// value of the wrong type was passed to
+	// a Codec.
+	UnsupportedType
+	// A field held a value which could not be
+	// represented in the field's width.
+	Overflow
+	// A buffer was too small to hold the packed data.
+	ShortBuffer
+	// A field held a value, such as a floating-point
+	// NaN or infinity, which has no representation
+	// in the field's encoding.
+	Unrepresentable
+	// Padding bits did not hold their
+	// expected value when unpacked.
+	BadPadding
+	// A field was placed at an offset
+	// which overlaps another field.
+	Overlap
+	// A BitPacker or BitUnpacker returned an
+	// error which did not specify a kind.
+	CustomEncoding
+	// A BitWriter or BitReader was given a
+	// width outside of the range [1, 64], or
+	// a negative number of bits to skip.
+	BadWidth
+	// A variable-length field's length did not
+	// match the field holding it, or could not
+	// be used to unpack the field.
+	BadLength
+	// A pointer field without the "optional"
+	// option was nil when packed.
+	NilPointer
+	// A union field held nil or a value whose
+	// type was not a registered variant, its
+	// switch field did not select a registered
+	// variant or did not match the value held,
+	// or a variant was registered twice or
+	// too late (see RegisterVariant).
+	BadVariant
+)
+
+var errorKindNames = [...]string{
+	BadTag:          "bad tag",
+	TagTooWide:      "tag too wide",
+	TagTooSmall:     "tag too small",
+	NonStruct:       "non-struct type",
+	UnsupportedType: "unsupported type",
+	Overflow:        "overflow",
+	ShortBuffer:     "short buffer",
+	Unrepresentable: "unrepresentable value",
+	BadPadding:      "bad padding",
+	Overlap:         "overlapping fields",
+	CustomEncoding:  "custom encoding error",
+	BadWidth:        "bad width",
+	BadLength:       "bad length",
+	NilPointer:      "nil pointer",
+	BadVariant:      "bad variant",
+}
+
+func (k ErrorKind) String() string {
+	if k > 0 && int(k) < len(errorKindNames) {
+		return errorKindNames[k]
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+func (k ErrorKind) Error() string {
+	return "gopack: " + k.String()
+}
+
+// Any panic originating from this package
+// will be of type Error, as will any error
+// returned from this package.
+type Error struct {
+	Kind ErrorKind
+
+	// Path is the dotted path of the field
+	// at fault relative to the top-level
+	// struct (for example, "File.Mode.User").
+	// Path is empty if the error does not
+	// concern a particular field.
+	Path string
+
+	// Offset and Width are the position and
+	// size, in bits, of the field at fault
+	// within the packed data. They are zero
+	// if the error does not concern a
+	// particular field or if the field's
+	// position was not yet known.
+	Offset, Width uint64
+
+	// Err describes the error in more detail.
+	// If it is nil, the error is described
+	// by Kind alone.
+	Err error
+}
+
+func (e Error) Error() string {
+	msg := e.Kind.String()
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Path == "" {
+		return "gopack: " + msg
+	}
+	return "gopack: " + e.Path + ": " + msg
+}
+
+// Unwrap returns e.Err.
+func (e Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is the ErrorKind of e.
+func (e Error) Is(target error) bool {
+	k, ok := target.(ErrorKind)
+	return ok && k == e.Kind
+}
+
+func newError(kind ErrorKind, format string, a ...interface{}) Error {
+	return Error{Kind: kind, Err: fmt.Errorf(format, a...)}
+}
+
+func newFieldError(kind ErrorKind, path string, offset, width uint64, format string, a ...interface{}) Error {
+	return Error{Kind: kind, Path: path, Offset: offset, Width: width, Err: fmt.Errorf(format, a...)}
+}
+
+// Returns the dotted path of the field
+// name within the struct at path.
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Returns the path of element i of
+// the array at path.
+func elemPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// Returns err, returned while packing or
+// unpacking a value at path whose bits start
+// at off, with its path and offset made
+// relative to the enclosing value.
+func relocateError(err error, path string, off uint64) error {
+	e, ok := err.(Error)
+	if !ok {
+		return err
+	}
+	switch {
+	case e.Path == "":
+		e.Path = path
+	case strings.HasPrefix(e.Path, "["):
+		e.Path = path + e.Path
+	default:
+		e.Path = fieldPath(path, e.Path)
+	}
+	e.Offset += off
+	return e
+}
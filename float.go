@@ -0,0 +1,176 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"math"
+	"reflect"
+)
+
+// floatEncoding describes how a float32 or
+// float64 field is stored. Unquantized fields
+// are stored as their raw IEEE 754 bits.
+// Quantized fields are stored as an integer
+// n such that the value is n*scale + bias.
+type floatEncoding struct {
+	width       uint8
+	native      bool // float32 or float64 width
+	quantized   bool
+	signed      bool
+	scale, bias float64
+}
+
+func getFloatEncoding(path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (floatEncoding, error) {
+	enc := floatEncoding{scale: 1}
+	t, err := parseTag(path, lsb, tag)
+	if err != nil {
+		return enc, err
+	}
+	if err := t.allow(path, lsb, "fixed", "scale", "bias", "signed"); err != nil {
+		return enc, err
+	}
+	bits, err := t.getWidth(path, lsb, typ, uint64(typ.Bits()))
+	if err != nil {
+		return enc, err
+	}
+	enc.width = uint8(bits)
+	// "offset" is accepted as another
+	// name for "bias" (see floatOffset)
+	bias := "bias"
+	if t.floatOffset(typ) {
+		if t.has("bias") {
+			return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: options \"bias\" and \"offset\" are mutually exclusive")
+		}
+		bias = "offset"
+	}
+	enc.quantized = t.has("fixed") || t.has("scale") || t.has(bias)
+	enc.signed = t.has("signed")
+	if !enc.quantized {
+		if enc.signed {
+			return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option \"signed\" requires \"fixed\" or \"scale\"")
+		}
+		if bits != uint64(typ.Bits()) {
+			return enc, newFieldError(BadTag, path, lsb, bits,
+				"bad struct tag: unquantized %v field must be %d bits", typ, typ.Bits())
+		}
+		enc.native = true
+		return enc, nil
+	}
+
+	if t.has("fixed") {
+		if t.has("scale") {
+			return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: options \"fixed\" and \"scale\" are mutually exclusive")
+		}
+		frac, err := t.getInt(path, lsb, "fixed", 0)
+		if err != nil {
+			return enc, err
+		}
+		if frac < 0 || frac > 64 {
+			return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: fractional bits out of range (%d)", frac)
+		}
+		enc.scale = math.Ldexp(1, -frac)
+	} else if enc.scale, err = t.getFloat(path, lsb, "scale", 1); err != nil {
+		return enc, err
+	}
+	if enc.bias, err = t.getFloat(path, lsb, bias, 0); err != nil {
+		return enc, err
+	}
+	if enc.scale == 0 || math.IsInf(enc.scale, 0) || math.IsNaN(enc.scale) {
+		return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: bad scale (%v)", enc.scale)
+	}
+	if math.IsInf(enc.bias, 0) || math.IsNaN(enc.bias) {
+		return enc, newFieldError(BadTag, path, lsb, 0, "bad struct tag: bad %s (%v)", bias, enc.bias)
+	}
+	return enc, nil
+}
+
+// Returns the range [min, max) of
+// integers which can be stored.
+func (enc floatEncoding) limits() (float64, float64) {
+	if enc.signed {
+		lim := math.Ldexp(1, int(enc.width)-1)
+		return -lim, lim
+	}
+	return 0, math.Ldexp(1, int(enc.width))
+}
+
+// Returns the value represented by the
+// stored integer n. The conversion prevents
+// the multiplication and addition from being
+// fused, so that the result is the same on
+// every host (and in code which gopackgen
+// generates).
+func (enc floatEncoding) value(n float64) float64 {
+	return float64(n*enc.scale) + enc.bias
+}
+
+func makeFloatPacker(order BitOrder, path string, lsb uint64, enc floatEncoding) packer {
+	put, _ := bitsFuncs(order)
+	width := enc.width
+	if enc.native {
+		if width == 32 {
+			return func(b []byte, field reflect.Value) error {
+				put(b, lsb, width, uint64(math.Float32bits(float32(field.Float()))))
+				return nil
+			}
+		}
+		return func(b []byte, field reflect.Value) error {
+			put(b, lsb, width, math.Float64bits(field.Float()))
+			return nil
+		}
+	}
+
+	min, max := enc.limits()
+	shift := 64 - width
+	return func(b []byte, field reflect.Value) error {
+		f := field.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return newFieldError(Unrepresentable, path, lsb, uint64(width), "value not representable: %v", f)
+		}
+		n := math.Floor((f-enc.bias)/enc.scale + 0.5)
+		if n < min || n >= max {
+			return newFieldError(Overflow, path, lsb, uint64(width), "value out of range: max %v, min %v; got %v",
+				enc.value(max-1), enc.value(min), f)
+		}
+		var u uint64
+		if enc.signed {
+			u = (uint64(int64(n)) << shift) >> shift
+		} else {
+			u = uint64(n)
+		}
+		put(b, lsb, width, u)
+		return nil
+	}
+}
+
+func makeFloatUnpacker(order BitOrder, lsb uint64, enc floatEncoding) unpacker {
+	_, get := bitsFuncs(order)
+	width := enc.width
+	if enc.native {
+		if width == 32 {
+			return func(b []byte, field reflect.Value) error {
+				field.SetFloat(float64(math.Float32frombits(uint32(get(b, lsb, width)))))
+				return nil
+			}
+		}
+		return func(b []byte, field reflect.Value) error {
+			field.SetFloat(math.Float64frombits(get(b, lsb, width)))
+			return nil
+		}
+	}
+
+	shift := 64 - width
+	return func(b []byte, field reflect.Value) error {
+		u := get(b, lsb, width)
+		var n float64
+		if enc.signed {
+			n = float64(int64(u<<shift) >> shift)
+		} else {
+			n = float64(u)
+		}
+		field.SetFloat(enc.value(n))
+		return nil
+	}
+}
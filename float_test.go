@@ -0,0 +1,161 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRawFloats(t *testing.T) {
+	type typ struct {
+		F1 float32
+		F2 float64
+	}
+
+	var b [12]byte
+	val := typ{1, -2}
+	Pack(b[:], val)
+	expect := [...]byte{0, 0, 0x80, 0x3F, 0, 0, 0, 0, 0, 0, 0, 0xC0}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	val = typ{float32(math.Inf(-1)), math.NaN()}
+	Pack(b[:], val)
+	Unpack(b[:], &val2)
+	if !math.IsInf(float64(val2.F1), -1) || !math.IsNaN(val2.F2) {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+}
+
+func TestCoverFloats(t *testing.T) {
+	rand.Seed(3317)
+	testCover(t, struct {
+		F1 bool
+		F2 float32
+		F3 uint8 `gopack:"3"`
+		F4 float64
+	}{})
+	testCoverOrder(t, MSBFirst, struct {
+		F1 bool
+		F2 [2]float32
+		F3 float64
+	}{})
+}
+
+func TestFixedPointFloats(t *testing.T) {
+	type typ struct {
+		F1 float64 `gopack:"12,fixed=4"`
+		F2 float32 `gopack:"12,fixed=4,signed"`
+	}
+
+	var b [3]byte
+	val := typ{3.25, -2.5}
+	Pack(b[:], val)
+	// F1 = 52, F2 = -40
+	expect := [...]byte{0x34, 0x80, 0xFD}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	// Values are rounded to the nearest representable value
+	Pack(b[:], typ{1.03, 0.97})
+	Unpack(b[:], &val2)
+	if val2 != (typ{1, 1}) {
+		t.Fatalf("Expected %v; got %v", typ{1, 1}, val2)
+	}
+
+	testError(t, Overflow, "gopack: F1: value out of range: max 255.9375, min 0; got 256", func() {
+		Pack(b[:], typ{256, 0})
+	})
+	testError(t, Overflow, "gopack: F1: value out of range: max 255.9375, min 0; got -1", func() {
+		Pack(b[:], typ{-1, 0})
+	})
+	testError(t, Overflow, "gopack: F2: value out of range: max 127.9375, min -128; got -128.5", func() {
+		Pack(b[:], typ{0, -128.5})
+	})
+	testError(t, Unrepresentable, "gopack: F1: value not representable: NaN", func() {
+		Pack(b[:], typ{math.NaN(), 0})
+	})
+	testError(t, Unrepresentable, "gopack: F2: value not representable: +Inf", func() {
+		Pack(b[:], typ{0, float32(math.Inf(1))})
+	})
+}
+
+func TestScaledFloats(t *testing.T) {
+	type sensor struct {
+		Temp     float64 `gopack:"10,scale=0.05,offset=-40"`
+		Humidity float32 `gopack:"7,fixed=0"`
+	}
+	type typ struct {
+		Sensor sensor
+	}
+
+	var b [3]byte
+	val := typ{sensor{-20, 55}}
+	Pack(b[:], val)
+	// Temp = 400, Humidity = 55
+	expect := [...]byte{0x90, 0xDD, 0x00}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if math.Abs(val2.Sensor.Temp-val.Sensor.Temp) > 0.025 || val2.Sensor.Humidity != 55 {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+}
+
+func TestFloatTagErrors(t *testing.T) {
+	type typ struct {
+		F1 float32 `gopack:"16"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: unquantized float32 field must be 32 bits", func() {
+		Pack(nil, typ{})
+	})
+
+	type typ1 struct {
+		F1 float64 `gopack:"8,fixed=2,scale=0.1"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: options \"fixed\" and \"scale\" are mutually exclusive", func() {
+		Pack(nil, typ1{})
+	})
+
+	type typ2 struct {
+		F1 float64 `gopack:"8,scale=0"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: bad scale (0)", func() {
+		Unpack(nil, &typ2{})
+	})
+
+	type typ3 struct {
+		F1 float32 `gopack:"33,fixed=1"`
+	}
+	testError(t, TagTooWide, "gopack: F1: struct tag too wide for type float32 (33)", func() {
+		Pack(nil, typ3{})
+	})
+
+	type typ4 struct {
+		F1 float32 `gopack:"8,fixed=1,units=C"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: unsupported option \"units\"", func() {
+		Pack(nil, typ4{})
+	})
+}
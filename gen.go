@@ -0,0 +1,54 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// Packer is implemented by types whose packing
+// methods were generated by the gopackgen command
+// (see github.com/synful/gopack/gopackgen). When
+// packing a Packer in the order given by its
+// BitOrder method, Pack and PackE call PackBits
+// instead of using reflection. PackBits must
+// behave exactly as PackE would, including the
+// errors it returns.
+//
+// PackedType returns the type for which the
+// methods were generated. The methods are only
+// used for values of that type (or pointers to
+// it), so that a type which embeds a Packer, and
+// so has its methods promoted, is still packed
+// in full.
+type Packer interface {
+	BitOrder() BitOrder
+	PackedType() reflect.Type
+	PackBits(b []byte) error
+}
+
+// Unpacker is the counterpart of Packer
+// which Unpack and UnpackE use.
+type Unpacker interface {
+	BitOrder() BitOrder
+	PackedType() reflect.Type
+	UnpackBits(b []byte) error
+}
+
+// Reports whether the methods of g, a Packer
+// or Unpacker whose dynamic type is typ, were
+// generated for typ (or the type it points to)
+// in order, rather than promoted from an
+// embedded field or generated for another order.
+func generatedFor(g interface {
+	BitOrder() BitOrder
+	PackedType() reflect.Type
+}, typ reflect.Type, order BitOrder) bool {
+	if g.BitOrder() != order {
+		return false
+	}
+	pt := g.PackedType()
+	return typ == pt || (typ.Kind() == reflect.Ptr && typ.Elem() == pt)
+}
@@ -0,0 +1,44 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// PackT is like PackE, but takes a pointer to the
+// value to pack, so that the value is not copied
+// into an interface{}. v must not be nil.
+func PackT[T any](b []byte, v *T) error {
+	if p, ok := any(v).(Packer); ok && generatedFor(p, reflect.TypeOf(v), LSBFirst) {
+		return p.PackBits(b)
+	}
+	return packValue(LSBFirst, b, reflect.ValueOf(v).Elem())
+}
+
+// UnpackT is like UnpackE, but returns the unpacked
+// value rather than storing it through a pointer,
+// and so cannot be mistakenly passed a non-pointer
+// value.
+func UnpackT[T any](b []byte) (T, error) {
+	var v T
+	if u, ok := any(&v).(Unpacker); ok && generatedFor(u, reflect.TypeOf(&v), LSBFirst) {
+		err := u.UnpackBits(b)
+		return v, err
+	}
+	err := unpackValue(LSBFirst, b, reflect.ValueOf(&v))
+	return v, err
+}
+
+// RegisterVariantT is like RegisterVariant, but
+// takes the interface type as a type parameter.
+//
+//	gopack.RegisterVariantT[Payload](1, Ping{})
+func RegisterVariantT[I any](key int64, v I) {
+	RegisterVariant(reflect.TypeOf((*I)(nil)).Elem(), key, v)
+}
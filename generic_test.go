@@ -0,0 +1,73 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package gopack
+
+import (
+	"testing"
+)
+
+func TestPackTUnpackT(t *testing.T) {
+	val := twelve{17, -40}
+	b := make([]byte, 2)
+	if err := PackT(b, &val); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var expect [2]byte
+	Pack(expect[:], val)
+	if string(b) != string(expect[:]) {
+		t.Errorf("Expected %v; got %v", expect, b)
+	}
+	val2, err := UnpackT[twelve](b)
+	if err != nil || val2 != val {
+		t.Errorf("Expected %v; got %v (error %v)", val, val2, err)
+	}
+
+	// Custom types, with methods on the
+	// value and on the pointer
+	m := mac{1, 2, 3, 4, 5, 6}
+	b = make([]byte, 6)
+	PackT(b, &m)
+	if m2, err := UnpackT[mac](b); err != nil || m2 != m {
+		t.Errorf("Expected %v; got %v (error %v)", m, m2, err)
+	}
+	PackT(b[:1], &ptrOnly{9})
+	if p, err := UnpackT[ptrOnly](b); err != nil || p.V != 9 {
+		t.Errorf("Expected 9; got %v (error %v)", p.V, err)
+	}
+
+	testCodecError(t, Overflow, "gopack: A: value out of range: max 31; got 32", PackT(b, &twelve{A: 32}))
+	testCodecError(t, ShortBuffer, "gopack: buffer too small (1; need 2)", PackT(b[:1], &val))
+	_, err = UnpackT[twelve](b[:1])
+	testCodecError(t, ShortBuffer, "gopack: buffer too small (1; need 2)", err)
+	_, err = UnpackT[int](b)
+	testCodecError(t, NonStruct, "gopack: non-struct type int", err)
+}
+
+func TestRegisterVariantT(t *testing.T) {
+	testError(t, BadVariant, "gopack: duplicate key 1 for variants gopack.unionPing and gopack.unionAck of gopack.unionOpen", func() {
+		RegisterVariantT[unionOpen](1, unionAck{})
+	})
+}
+
+func BenchmarkPackT(b *testing.B) {
+	buf := make([]byte, PackedSizeof(benchSample{}))
+	var s benchSample
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PackT(buf, &s)
+	}
+}
+
+func BenchmarkPackValue(b *testing.B) {
+	buf := make([]byte, PackedSizeof(benchSample{}))
+	var s benchSample
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Pack(buf, s)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// The packed format must not depend on the host,
+// so these tests check packed bytes against fixed
+// values. On hosts which use the unsafe packers,
+// both the unsafe and the portable packers are
+// tested.
+
+type goldenCase struct {
+	val      interface{}
+	lsb, msb []byte
+}
+
+var goldenCases = []goldenCase{
+	{
+		struct {
+			F1 uint8  `gopack:"3"`
+			F2 uint16 `gopack:"13"`
+			F3 int8   `gopack:"4"`
+			F4 bool
+		}{5, 0x1ABC, -2, true},
+		[]byte{0xE5, 0xD5, 0x1E},
+		[]byte{0xBA, 0xBC, 0xE8},
+	},
+	{
+		struct {
+			F1 uint64
+			F2 uint8 `gopack:"7"`
+		}{0x0123456789ABCDEF, 0x55},
+		[]byte{0xEF, 0xCD, 0xAB, 0x89, 0x67, 0x45, 0x23, 0x01, 0x55},
+		[]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF, 0xAA},
+	},
+	{
+		struct {
+			F1 uint8 `gopack:"4"`
+			F2 uint64
+		}{0xF, 0x0123456789ABCDEF},
+		[]byte{0xFF, 0xDE, 0xBC, 0x9A, 0x78, 0x56, 0x34, 0x12, 0x00},
+		[]byte{0xF0, 0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0},
+	},
+}
+
+// Calls f once for each packer implementation
+// available on this host.
+func forEachImpl(t *testing.T, f func(impl string)) {
+	defer func(old bool) { useUnsafePackers = old }(useUnsafePackers)
+	if unalignedLittleEndian {
+		useUnsafePackers = true
+		f("unsafe")
+	}
+	useUnsafePackers = false
+	f("portable")
+}
+
+func TestGolden(t *testing.T) {
+	forEachImpl(t, func(impl string) {
+		for _, c := range goldenCases {
+			testGolden(t, impl, LSBFirst, c.val, c.lsb)
+			testGolden(t, impl, MSBFirst, c.val, c.msb)
+		}
+	})
+}
+
+func testGolden(t *testing.T, impl string, order BitOrder, val interface{}, expect []byte) {
+	typ := reflect.TypeOf(val)
+	p, n, err := makePackerWrapper(typ, order)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u, err := makeUnpackerWrapper(reflect.PtrTo(typ), order)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	b := make([]byte, n)
+	if err := p(b, reflect.ValueOf(val)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, expect) {
+		t.Errorf("%v, %v: expected %#v; got %#v (on type %v)", impl, order, expect, b, typ)
+	}
+	val2 := reflect.New(typ)
+	if err := u(expect, val2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val2.Elem().Interface() != val {
+		t.Errorf("%v, %v: expected %v; got %v", impl, order, val, val2.Elem().Interface())
+	}
+}
+
+// Make sure that the unsafe and portable packers
+// agree on every offset and width.
+func TestPortableMatchesUnsafe(t *testing.T) {
+	if !unalignedLittleEndian {
+		t.Skip("unsafe packers not used on this host")
+	}
+	rand.Seed(6121)
+	defer func(old bool) { useUnsafePackers = old }(useUnsafePackers)
+
+	b1 := make([]byte, 18)
+	b2 := make([]byte, 18)
+	for lsb := uint64(0); lsb < 72; lsb++ {
+		for width := uint8(1); width <= 64; width++ {
+			typ := reflect.TypeOf(uint64(0))
+			useUnsafePackers = true
+			pu := makeUnsignedPacker(LSBFirst, "", typ, lsb, width)
+			ps := makeSignedPacker(LSBFirst, "", reflect.TypeOf(int64(0)), lsb, width)
+			useUnsafePackers = false
+			qu := makeUnsignedPacker(LSBFirst, "", typ, lsb, width)
+			qs := makeSignedPacker(LSBFirst, "", reflect.TypeOf(int64(0)), lsb, width)
+
+			for i := 0; i < 16; i++ {
+				u := randUint64Bits(width)
+				s := randInt64Bits(width)
+				for _, c := range []struct {
+					p, q packer
+					v    reflect.Value
+				}{{pu, qu, reflect.ValueOf(u)}, {ps, qs, reflect.ValueOf(s)}} {
+					for j := range b1 {
+						b1[j], b2[j] = 0, 0
+					}
+					c.p(b1, c.v)
+					c.q(b2, c.v)
+					if !bytes.Equal(b1, b2) {
+						t.Fatalf("lsb %v, width %v, value %v: unsafe packed %v; portable packed %v",
+							lsb, width, c.v, b1, b2)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCoverPortable(t *testing.T) {
+	rand.Seed(4490)
+	defer func(old bool) { useUnsafePackers = old }(useUnsafePackers)
+	useUnsafePackers = false
+	testCover(t, struct {
+		F1 uint8 `gopack:"7"`
+		F2 uint64
+		F3 int64 `gopack:"63"`
+		F4 bool
+	}{})
+	testCover(t, struct {
+		F1 int8   `gopack:"3"`
+		F2 uint32 `gopack:"29"`
+		F3 int16
+	}{})
+}
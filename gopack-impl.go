@@ -5,221 +5,229 @@
 package gopack
 
 import (
-	"fmt"
 	"reflect"
-	"strconv"
 )
 
-type packer func(b []byte, v reflect.Value)
-type unpacker func(b []byte, v reflect.Value)
+type packer func(b []byte, v reflect.Value) error
+type unpacker func(b []byte, v reflect.Value) error
 
-func makePackerWrapper(strct reflect.Type) (packer, int) {
-	p, bits, err := makePacker(0, strct)
+func makePackerWrapper(strct reflect.Type, order BitOrder) (packer, int, error) {
+	c := makeCachedPacker(strct, order)
+	return c.packer, c.bytes, c.err
+}
+
+func makeCachedPacker(strct reflect.Type, order BitOrder) cachedPacker {
+	pl, err := planFor(strct)
 	if err != nil {
-		panic(err)
+		return cachedPacker{err: err}
 	}
-	bytes := int(bits) / 8
-	if bits%8 != 0 {
-		bytes++
+	if pl.varSize {
+		return makeVarCachedPacker(pl, strct, order)
+	}
+	p, err := pl.packer(order, strct.Kind() == reflect.Ptr)
+	if err != nil {
+		return cachedPacker{err: err}
 	}
-	return p, bytes
+	return cachedPacker{packer: p, bits: pl.bits, bytes: bitsToBytes(pl.bits)}
 }
 
-func makeUnpackerWrapper(strct reflect.Type) unpacker {
-	u, bits, err := makeUnpacker(0, strct)
+func makeUnpackerWrapper(strct reflect.Type, order BitOrder) (unpacker, error) {
+	c := makeCachedUnpacker(strct, order)
+	return c.unpacker, c.err
+}
+
+func makeCachedUnpacker(strct reflect.Type, order BitOrder) cachedUnpacker {
+	pl, err := planFor(strct)
 	if err != nil {
-		return func(b []byte, v reflect.Value) {
-			panic(err)
-		}
+		return cachedUnpacker{err: err}
 	}
+	if pl.varSize {
+		return makeVarCachedUnpacker(pl, strct, order)
+	}
+	u, err := pl.unpacker(order, strct.Kind() == reflect.Ptr)
+	if err != nil {
+		return cachedUnpacker{err: err}
+	}
+	bytes := bitsToBytes(pl.bits)
 	// Check for non-pointers after
 	// checking for errors so that
 	// passing a non-pointer value
-	// with an invalid type panics
-	// (as opposed to being a no-op)
+	// with an invalid type reports
+	// an error (as opposed to being
+	// a no-op)
 	if strct.Kind() != reflect.Ptr {
-		return noOpUnpacker
+		return cachedUnpacker{unpacker: noOpUnpacker, bits: pl.bits, bytes: bytes}
 	}
-	bytes := int(bits) / 8
-	if bits%8 != 0 {
-		bytes++
-	}
-	return func(b []byte, v reflect.Value) {
+	return cachedUnpacker{unpacker: func(b []byte, v reflect.Value) error {
 		if len(b) < bytes {
-			panic(Error{fmt.Errorf("gopack: buffer too small (%v; need %v)", len(b), bytes)})
+			return newError(ShortBuffer, "buffer too small (%v; need %v)", len(b), bytes)
 		}
-		u(b, v)
-	}
+		return u(b, v)
+	}, bits: pl.bits, bytes: bytes}
 }
 
-// Returns the number of bits packed
-// as the second return value
-func makePacker(lsb uint64, strct reflect.Type) (packer, uint64, error) {
-	ptrType := strct.Kind() == reflect.Ptr
-	if ptrType {
-		strct = strct.Elem()
-	}
-	if strct.Kind() != reflect.Struct {
-		return nil, 0, Error{fmt.Errorf("gopack: non-struct type %v", strct.String())}
-	}
-	n := strct.NumField()
-	packers := make([]packer, 0)
-	var bitsPacked uint64
-	for i := 0; i < n; i++ {
-		field := strct.Field(i)
-		if isExported(field) {
-			f, bits, err := makeFieldPacker(lsb, field)
-			if err != nil {
-				return nil, 0, err
-			}
-			lsb += bits
-			bitsPacked += bits
-			packers = append(packers, f)
-		} else {
-			packers = append(packers, noOpPacker)
-		}
+// Extend b by n zero bytes
+func growZero(b []byte, n int) []byte {
+	l := len(b)
+	if cap(b)-l < n {
+		buf := make([]byte, l, 2*cap(b)+n)
+		copy(buf, b)
+		b = buf
+	}
+	b = b[:l+n]
+	for i := l; i < len(b); i++ {
+		b[i] = 0
 	}
-	return makeCallAllPackers(packers, ptrType), bitsPacked, nil
+	return b
 }
 
-// Returns the number of bits packed
-// as the second return value
-func makeFieldPacker(lsb uint64, field reflect.StructField) (packer, uint64, error) {
-	switch field.Type.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		bits, err := getFieldWidth(field)
-		if err != nil {
-			return nil, 0, err
-		}
-		return makeSignedSinglePacker(field.Type, lsb, uint8(bits)), bits, nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		bits, err := getFieldWidth(field)
-		if err != nil {
-			return nil, 0, err
-		}
-		return makeUnsignedSinglePacker(field.Type, lsb, uint8(bits)), bits, nil
-	case reflect.Bool:
-		return makeBoolSinglePacker(lsb), 1, nil
-	case reflect.Struct:
-		return makePacker(lsb, field.Type)
-	default:
-		return nil, 0, Error{fmt.Errorf("gopack: non-packable type %v", field.Type.String())}
+// Returns the number of bytes
+// needed to hold bits bits
+func bitsToBytes(bits uint64) int {
+	bytes := int(bits) / 8
+	if bits%8 != 0 {
+		bytes++
 	}
+	return bytes
 }
 
 func makeCallAllPackers(p []packer, ptrType bool) packer {
 	if ptrType {
-		return func(b []byte, v reflect.Value) {
+		return func(b []byte, v reflect.Value) error {
 			v = v.Elem()
 			for i, f := range p {
-				f(b, v.Field(i))
+				if err := f(b, v.Field(i)); err != nil {
+					return err
+				}
 			}
+			return nil
 		}
 	} else {
-		return func(b []byte, v reflect.Value) {
+		return func(b []byte, v reflect.Value) error {
 			for i, f := range p {
-				f(b, v.Field(i))
+				if err := f(b, v.Field(i)); err != nil {
+					return err
+				}
 			}
+			return nil
 		}
 	}
 }
 
-func noOpPacker(b []byte, v reflect.Value) {}
-
-// Returns the number of bits unpacked
-// as the second return value
-func makeUnpacker(lsb uint64, strct reflect.Type) (unpacker, uint64, error) {
-	ptrType := strct.Kind() == reflect.Ptr
-	if ptrType {
-		strct = strct.Elem()
-	}
-	if strct.Kind() != reflect.Struct {
-		return nil, 0, Error{fmt.Errorf("gopack: non-struct type %v", strct.String())}
-	}
-	n := strct.NumField()
-	unpackers := make([]unpacker, 0)
-	var bitsUnpacked uint64
-	for i := 0; i < n; i++ {
-		field := strct.Field(i)
-		if isExported(field) {
-			f, bits, err := makeFieldUnpacker(lsb, field)
-			if err != nil {
-				return nil, 0, err
+func makeCallAllElemPackers(p []packer) packer {
+	return func(b []byte, v reflect.Value) error {
+		for i, f := range p {
+			if err := f(b, v.Index(i)); err != nil {
+				return err
 			}
-			lsb += bits
-			bitsUnpacked += bits
-			unpackers = append(unpackers, f)
-		} else {
-			unpackers = append(unpackers, noOpUnpacker)
 		}
+		return nil
 	}
-	return makeCallAllUnpackers(unpackers, ptrType), bitsUnpacked, nil
 }
 
-// Returns the number of bits unpacked
-// as the second return value
-func makeFieldUnpacker(lsb uint64, field reflect.StructField) (unpacker, uint64, error) {
-	switch field.Type.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		bits, err := getFieldWidth(field)
-		if err != nil {
-			return nil, 0, err
-		}
-		return makeSignedSingleUnpacker(field.Type, lsb, uint8(bits)), bits, nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		bits, err := getFieldWidth(field)
-		if err != nil {
-			return nil, 0, err
-		}
-		return makeUnsignedSingleUnpacker(field.Type, lsb, uint8(bits)), bits, nil
-	case reflect.Bool:
-		return makeBoolSingleUnpacker(lsb), 1, nil
-	case reflect.Struct:
-		return makeUnpacker(lsb, field.Type)
-	default:
-		return nil, 0, Error{fmt.Errorf("gopack: non-packable type %v", field.Type.String())}
-	}
-}
+func noOpPacker(b []byte, v reflect.Value) error { return nil }
 
 func makeCallAllUnpackers(u []unpacker, ptrType bool) unpacker {
 	if ptrType {
-		return func(b []byte, v reflect.Value) {
+		return func(b []byte, v reflect.Value) error {
 			v = v.Elem()
 			for i, f := range u {
-				f(b, v.Field(i))
+				if err := f(b, v.Field(i)); err != nil {
+					return err
+				}
 			}
+			return nil
 		}
 	} else {
-		return func(b []byte, v reflect.Value) {
+		return func(b []byte, v reflect.Value) error {
 			for i, f := range u {
-				f(b, v.Field(i))
+				if err := f(b, v.Field(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func makeCallAllElemUnpackers(u []unpacker) unpacker {
+	return func(b []byte, v reflect.Value) error {
+		for i, f := range u {
+			if err := f(b, v.Index(i)); err != nil {
+				return err
 			}
 		}
+		return nil
+	}
+}
+
+func noOpUnpacker(b []byte, v reflect.Value) error { return nil }
+
+// The packers in pack.go are only used for
+// LSBFirst order, and only on hosts where
+// they produce the same bytes as the
+// portable packers in pack-portable.go.
+var useUnsafePackers = unalignedLittleEndian
+
+func makeUnsignedPacker(order BitOrder, path string, typ reflect.Type, lsb uint64, width uint8) packer {
+	if order == LSBFirst && useUnsafePackers {
+		return makeUnsignedSinglePacker(path, typ, lsb, width)
 	}
+	put, _ := bitsFuncs(order)
+	return makeUnsignedBitsPacker(path, typ, lsb, width, put)
 }
 
-func noOpUnpacker(b []byte, v reflect.Value) {}
+func makeSignedPacker(order BitOrder, path string, typ reflect.Type, lsb uint64, width uint8) packer {
+	if order == LSBFirst && useUnsafePackers {
+		return makeSignedSinglePacker(path, typ, lsb, width)
+	}
+	put, _ := bitsFuncs(order)
+	return makeSignedBitsPacker(path, typ, lsb, width, put)
+}
+
+func makeBoolPacker(order BitOrder, lsb uint64) packer {
+	if order == LSBFirst && useUnsafePackers {
+		return makeBoolSinglePacker(lsb)
+	}
+	put, _ := bitsFuncs(order)
+	return makeBoolBitsPacker(lsb, put)
+}
+
+func makeUnsignedUnpacker(order BitOrder, typ reflect.Type, lsb uint64, width uint8) unpacker {
+	if order == LSBFirst && useUnsafePackers {
+		return makeUnsignedSingleUnpacker(typ, lsb, width)
+	}
+	_, get := bitsFuncs(order)
+	return makeUnsignedBitsUnpacker(lsb, width, get)
+}
 
-// Only call on uint and int types
-func getFieldWidth(field reflect.StructField) (uint64, error) {
-	bits := uint64(field.Type.Bits())
-	str := field.Tag.Get("gopack")
-	if str == "" {
-		return bits, nil
+func makeSignedUnpacker(order BitOrder, typ reflect.Type, lsb uint64, width uint8) unpacker {
+	if order == LSBFirst && useUnsafePackers {
+		return makeSignedSingleUnpacker(typ, lsb, width)
 	}
+	_, get := bitsFuncs(order)
+	return makeSignedBitsUnpacker(lsb, width, get)
+}
 
-	n, err := strconv.Atoi(str)
+func makeBoolUnpacker(order BitOrder, lsb uint64) unpacker {
+	if order == LSBFirst && useUnsafePackers {
+		return makeBoolSingleUnpacker(lsb)
+	}
+	_, get := bitsFuncs(order)
+	return makeBoolBitsUnpacker(lsb, get)
+}
+
+// Only call on uint and int types. path
+// and lsb are used only for error reporting.
+func getFieldWidth(path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (uint64, error) {
+	t, err := parseTag(path, lsb, tag)
 	if err != nil {
-		return 0, Error{fmt.Errorf("gopack: struct tag on field %q: %s",
-			field.Name, err)}
-	} else if n > int(bits) {
-		return 0, Error{fmt.Errorf("gopack: struct tag on field %q (type %s) too wide (%d)",
-			field.Name, field.Type, n)}
-	} else if n < 1 {
-		return 0, Error{fmt.Errorf("gopack: struct tag on field %q too small (%d)",
-			field.Name, n)}
-	}
-	return uint64(n), nil
+		return 0, err
+	}
+	if err := t.allow(path, lsb); err != nil {
+		return 0, err
+	}
+	return t.getWidth(path, lsb, typ, uint64(typ.Bits()))
 }
 
 func isExported(field reflect.StructField) bool {
@@ -5,15 +5,44 @@
 package gopack
 
 import (
-	"fmt"
+	"errors"
 	"math"
 	"math/rand"
 	"reflect"
+	"strconv"
 	"testing"
 
 	gopack_testing "github.com/synful/gopack/testing"
 )
 
+// Makes a packer for strct without going
+// through the caches. Returns the number of
+// bits packed as the second return value.
+func makePacker(order BitOrder, path string, lsb uint64, strct reflect.Type) (packer, uint64, error) {
+	pl, err := makePlan(path, lsb, strct)
+	if err != nil {
+		return nil, 0, err
+	}
+	p, err := pl.packer(order, strct.Kind() == reflect.Ptr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, pl.bits, nil
+}
+
+// The inverse of makePacker
+func makeUnpacker(order BitOrder, path string, lsb uint64, strct reflect.Type) (unpacker, uint64, error) {
+	pl, err := makePlan(path, lsb, strct)
+	if err != nil {
+		return nil, 0, err
+	}
+	u, err := pl.unpacker(order, strct.Kind() == reflect.Ptr)
+	if err != nil {
+		return nil, 0, err
+	}
+	return u, pl.bits, nil
+}
+
 func TestMakePacker(t *testing.T) {
 	type typ struct {
 		f1 uint8
@@ -198,9 +227,13 @@ func TestCoverSigned(t *testing.T) {
 }
 
 func testCover(t *testing.T, v interface{}) {
+	testCoverOrder(t, LSBFirst, v)
+}
+
+func testCoverOrder(t *testing.T, order BitOrder, v interface{}) {
 	typ := reflect.TypeOf(v)
-	p, n := makePackerWrapper(typ)
-	u := makeUnpackerWrapper(reflect.PtrTo(typ))
+	p, n, _ := makePackerWrapper(typ, order)
+	u, _ := makeUnpackerWrapper(reflect.PtrTo(typ), order)
 
 	// Note: increasing the iterations to 1000*1000
 	// will cause the full test suite to take ~30s
@@ -211,7 +244,7 @@ func testCover(t *testing.T, v interface{}) {
 		p(b, val1)
 		u(b, val2)
 		if val2.Elem().Interface() != val1.Interface() {
-			t.Fatalf("Expected \n%v; got \n%v\n(on type %v)", val1.Interface(), val2.Elem().Interface(), typ)
+			t.Fatalf("Expected \n%v; got \n%v\n(on type %v, order %v)", val1.Interface(), val2.Elem().Interface(), typ, order)
 		}
 	}
 }
@@ -244,8 +277,8 @@ func TestByteBoundaries(t *testing.T) {
 	}
 
 	val := typ{}
-	p, _ := makePackerWrapper(reflect.TypeOf(val))
-	u := makeUnpackerWrapper(reflect.TypeOf(&val))
+	p, _, _ := makePackerWrapper(reflect.TypeOf(val), LSBFirst)
+	u, _ := makeUnpackerWrapper(reflect.TypeOf(&val), LSBFirst)
 
 	for i := 0; i < 1000*1000; i++ {
 		var b [32]byte
@@ -340,6 +373,66 @@ func TestBoolTag(t *testing.T) {
 	}
 }
 
+func TestArrays(t *testing.T) {
+	type pair struct {
+		A, B bool
+	}
+	type typ struct {
+		Channels [4]uint8 `gopack:"4"`
+		Pairs    [2]pair
+		Grid     [2][2]int8 `gopack:"2"`
+	}
+
+	if sz := PackedSizeof(typ{}); sz != 4 {
+		t.Errorf("Expected a packed size of 4 but got %d", sz)
+	}
+
+	var b [4]byte
+	val := typ{
+		Channels: [4]uint8{1, 2, 3, 15},
+		Pairs:    [2]pair{{true, false}, {false, true}},
+		Grid:     [2][2]int8{{-1, 0}, {1, -2}},
+	}
+	Pack(b[:], val)
+	if b != [...]byte{0x21, 0xF3, 0x39, 0x09} {
+		t.Fatalf("Expected %v; got %v", [...]byte{0x21, 0xF3, 0x39, 0x09}, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	val.Channels[3] = 16
+	testError(t, Overflow, "gopack: Channels[3]: value out of range: max 15; got 16", func() {
+		Pack(b[:], val)
+	})
+
+	type typ1 struct {
+		F1 [2]complex64
+	}
+	testError(t, UnsupportedType, "gopack: F1[0]: non-packable type complex64", func() {
+		Pack(b[:], typ1{})
+	})
+}
+
+func TestCoverArrays(t *testing.T) {
+	rand.Seed(2716)
+	testCover(t, struct {
+		F1 [3]uint16 `gopack:"11"`
+		F2 [5]bool
+		F3 [2]struct {
+			F1 int8 `gopack:"5"`
+			F2 [2]uint64
+		}
+	}{})
+	testCover(t, struct {
+		F1 [0]uint8
+		F2 [2][3]int32 `gopack:"17"`
+	}{})
+}
+
 func TestUnexported(t *testing.T) {
 	var b [1]byte
 	val := gopack_testing.MakeTyp("hi", 255)
@@ -483,56 +576,56 @@ func Test64Bit(t *testing.T) {
 
 func TestErrors(t *testing.T) {
 	i := 0
-	testError(t, Error{fmt.Errorf("gopack: non-struct type int")}, func() {
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
 		Pack(nil, 0)
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-struct type int")}, func() {
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
 		Pack(nil, &i)
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-struct type int")}, func() {
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
 		Unpack(nil, i)
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-struct type int")}, func() {
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
 		Pack(nil, i)
 	})
 
 	type typ struct {
-		F1 string
+		F1 complex64
 	}
-	testError(t, Error{fmt.Errorf("gopack: non-packable type string")}, func() {
+	testError(t, UnsupportedType, "gopack: F1: non-packable type complex64", func() {
 		Pack(nil, typ{})
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-packable type string")}, func() {
+	testError(t, UnsupportedType, "gopack: F1: non-packable type complex64", func() {
 		Unpack(nil, typ{})
 	})
 
 	type typ1 struct {
 		F1 uint8 `gopack:"numerals"`
 	}
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\": strconv.ParseInt: parsing \"numerals\": invalid syntax")}, func() {
+	testError(t, BadTag, "gopack: F1: bad struct tag: strconv.ParseInt: parsing \"numerals\": invalid syntax", func() {
 		Pack(nil, typ1{})
 	})
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\": strconv.ParseInt: parsing \"numerals\": invalid syntax")}, func() {
+	testError(t, BadTag, "gopack: F1: bad struct tag: strconv.ParseInt: parsing \"numerals\": invalid syntax", func() {
 		Unpack(nil, typ1{})
 	})
 
 	type typ2 struct {
 		F1 int8 `gopack:"9"`
 	}
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\" (type int8) too wide (9)")}, func() {
+	testError(t, TagTooWide, "gopack: F1: struct tag too wide for type int8 (9)", func() {
 		Pack(nil, typ2{})
 	})
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\" (type int8) too wide (9)")}, func() {
+	testError(t, TagTooWide, "gopack: F1: struct tag too wide for type int8 (9)", func() {
 		Unpack(nil, typ2{})
 	})
 
 	type typ3 struct {
 		F1 uint8 `gopack:"0"`
 	}
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\" too small (0)")}, func() {
+	testError(t, TagTooSmall, "gopack: F1: struct tag too small (0)", func() {
 		Pack(nil, typ3{})
 	})
-	testError(t, Error{fmt.Errorf("gopack: struct tag on field \"F1\" too small (0)")}, func() {
+	testError(t, TagTooSmall, "gopack: F1: struct tag too small (0)", func() {
 		Pack(nil, typ3{})
 	})
 
@@ -540,15 +633,15 @@ func TestErrors(t *testing.T) {
 		F1 uint8 `gopack:"4"`
 		F2 int8  `gopack:"4"`
 	}
-	testError(t, Error{fmt.Errorf("gopack: value out of range: max 15; got 16")}, func() {
+	testError(t, Overflow, "gopack: F1: value out of range: max 15; got 16", func() {
 		Pack(make([]byte, 1), typ4{15, 0})
 		Pack(make([]byte, 1), typ4{16, 0})
 	})
-	testError(t, Error{fmt.Errorf("gopack: value out of range: max 7, min -8; got 8")}, func() {
+	testError(t, Overflow, "gopack: F2: value out of range: max 7, min -8; got 8", func() {
 		Pack(make([]byte, 1), typ4{15, -8})
 		Pack(make([]byte, 1), typ4{15, 8})
 	})
-	testError(t, Error{fmt.Errorf("gopack: value out of range: max 7, min -8; got -9")}, func() {
+	testError(t, Overflow, "gopack: F2: value out of range: max 7, min -8; got -9", func() {
 		Pack(make([]byte, 1), typ4{15, -8})
 		Pack(make([]byte, 1), typ4{15, -9})
 	})
@@ -557,12 +650,12 @@ func TestErrors(t *testing.T) {
 		F1 uint8
 	}
 	type typ6 struct {
-		F1 *typ5
+		F1 chan typ5
 	}
-	testError(t, Error{fmt.Errorf("gopack: non-packable type *gopack.typ5")}, func() {
+	testError(t, UnsupportedType, "gopack: F1: non-packable type chan gopack.typ5", func() {
 		Pack(nil, typ6{})
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-packable type *gopack.typ5")}, func() {
+	testError(t, UnsupportedType, "gopack: F1: non-packable type chan gopack.typ5", func() {
 		Unpack(nil, typ6{})
 	})
 
@@ -573,10 +666,10 @@ func TestErrors(t *testing.T) {
 
 	t1 := typ7{255, 255}
 	bytes := []byte{}
-	testError(t, Error{fmt.Errorf("gopack: buffer too small (0; need 2)")}, func() {
+	testError(t, ShortBuffer, "gopack: buffer too small (0; need 2)", func() {
 		Pack(bytes, t1)
 	})
-	testError(t, Error{fmt.Errorf("gopack: buffer too small (0; need 2)")}, func() {
+	testError(t, ShortBuffer, "gopack: buffer too small (0; need 2)", func() {
 		Unpack(bytes, &t1)
 	})
 
@@ -585,31 +678,87 @@ func TestErrors(t *testing.T) {
 	}
 
 	t2 := typ8{255, 255, 255, 255, 255, 255, 255, 255}
-	testError(t, Error{fmt.Errorf("gopack: buffer too small (0; need 8)")}, func() {
+	testError(t, ShortBuffer, "gopack: buffer too small (0; need 8)", func() {
 		Pack(bytes, t2)
 	})
-	testError(t, Error{fmt.Errorf("gopack: buffer too small (0; need 8)")}, func() {
+	testError(t, ShortBuffer, "gopack: buffer too small (0; need 8)", func() {
 		Unpack(bytes, &t2)
 	})
 
 	// Make sure that Unpack reports errors
 	// even for non-pointer types
-	testError(t, Error{fmt.Errorf("gopack: non-struct type int")}, func() {
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
 		Unpack(nil, 0)
 	})
-	testError(t, Error{fmt.Errorf("gopack: non-packable type *uint8")}, func() {
+	testError(t, UnsupportedType, "gopack: F1: non-packable type chan uint8", func() {
 		type typ9 struct {
-			F1 *uint8
+			F1 chan uint8
 		}
 		Unpack(nil, typ9{})
 	})
 }
 
-func testError(t *testing.T, err interface{}, f func()) {
+func TestErrorFields(t *testing.T) {
+	type mode struct {
+		User, Group, Other uint8 `gopack:"3"`
+	}
+	type file struct {
+		Size uint8
+		Mode mode
+	}
+	type typ struct {
+		Open bool
+		File file
+	}
+
+	err := PackE(make([]byte, 3), typ{File: file{Mode: mode{Group: 8}}})
+	var e Error
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected error of type Error; got %T (%v)", err, err)
+	}
+	if !errors.Is(err, Overflow) || errors.Is(err, ShortBuffer) {
+		t.Errorf("Expected errors.Is to match only kind %v; got %v", Overflow, e.Kind)
+	}
+	if e.Path != "File.Mode.Group" || e.Offset != 12 || e.Width != 3 {
+		t.Errorf("Expected path File.Mode.Group, offset 12, width 3; got %v, %v, %v",
+			e.Path, e.Offset, e.Width)
+	}
+
+	type typ1 struct {
+		F1 uint8
+		F2 uint8 `gopack:"bits"`
+	}
+	err = UnpackE(nil, &typ1{})
+	if !errors.As(err, &e) || e.Kind != BadTag || e.Path != "F2" || e.Offset != 8 {
+		t.Errorf("Expected BadTag error on F2 at offset 8; got %#v", err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("Expected error to wrap *strconv.NumError")
+	}
+
+	if err = UnpackE(nil, &typ{}); !errors.Is(err, ShortBuffer) {
+		t.Errorf("Expected %v error; got %v", ShortBuffer, err)
+	}
+
+	// Without Err, an Error is described by its kind
+	if s := (Error{}).Error(); s != "gopack: ErrorKind(0)" {
+		t.Errorf("Unexpected message %q", s)
+	}
+	if s := (Error{Kind: Overflow, Path: "F"}).Error(); s != "gopack: F: overflow" {
+		t.Errorf("Unexpected message %q", s)
+	}
+}
+
+func testError(t *testing.T, kind ErrorKind, msg string, f func()) {
 	defer func() {
 		r := recover()
-		if r.(Error).Error() != err.(Error).Error() {
-			t.Fatalf("Expected error \"%v\"; got \"%v\"", err, r)
+		err, ok := r.(Error)
+		if !ok {
+			t.Fatalf("Expected panic of type Error; got %T (%v)", r, r)
+		}
+		if err.Kind != kind || err.Error() != msg {
+			t.Fatalf("Expected error \"%v\" (%v); got \"%v\" (%v)", msg, kind, err, err.Kind)
 		}
 	}()
 	f()
@@ -6,36 +6,77 @@
 package gopack
 
 import (
-	"fmt"
 	"reflect"
 	"sync"
 )
 
-// Any panic originating from this package
-// will be of type Error.
-type Error struct {
-	error
-}
-
 type cachedPacker struct {
 	packer
+	bits  uint64
 	bytes int
+	// For variable-size types, returns the
+	// number of bits needed to pack a value
+	size func(v reflect.Value) uint64
+	err  error
+}
+
+// Returns the number of bits and
+// bytes needed to pack v.
+func (c cachedPacker) sizeof(v reflect.Value) (uint64, int) {
+	if c.size == nil {
+		return c.bits, c.bytes
+	}
+	bits := c.size(v)
+	return bits, bitsToBytes(bits)
 }
 
-var packerCache struct {
-	sync.RWMutex
-	m map[reflect.Type]cachedPacker
+type cachedUnpacker struct {
+	unpacker
+	// For variable-size types, the
+	// smallest size of a value
+	bits     uint64
+	bytes    int
+	variable bool
+	// For variable-size types, unpacks a
+	// value starting at a bit offset, and
+	// returns the offset of its end
+	unpackFrom varUnpacker
+	err        error
+}
+
+// Packers and unpackers depend on both
+// the type and the bit order.
+type cacheKey struct {
+	typ   reflect.Type
+	order BitOrder
+}
+
+// The caches map cacheKeys to *packerEntry and
+// *unpackerEntry values. Lookups of types which
+// are already cached take no locks. An entry is
+// stored before it is constructed so that if
+// several goroutines miss at once, only one of
+// them constructs it and the rest wait for it.
+// Both are derived from the type's plan (see
+// planFor), which is shared between them.
+var packerCache, unpackerCache sync.Map
+
+type packerEntry struct {
+	once sync.Once
+	cachedPacker
 }
 
-var unpackerCache struct {
-	sync.RWMutex
-	m map[reflect.Type]unpacker
+type unpackerEntry struct {
+	once sync.Once
+	cachedUnpacker
 }
 
 // Pack the fields of strct into b. Fields must be
 // of an int or bool type, or must be of a struct
 // type whose fields are properly typed (structs
-// may be nested arbitrarily deep). If strct is not
+// may be nested arbitrarily deep), or must be of
+// a float type, or must be arrays of any of these
+// types. If strct is not
 // a struct or a pointer to a struct, or if any
 // of the fields are not of an allowed type, Pack
 // will panic.
@@ -61,6 +102,130 @@ var unpackerCache struct {
 // bool-typed fields always take up 1 bit, and any field
 // tags are ignored.
 //
+// float32- and float64-typed fields are stored as
+// their raw IEEE 754 bits by default. They may instead
+// be quantized to an integer of the tagged width,
+// either as a fixed-point number with a given number
+// of fractional bits, or with a given scale and
+// offset (so that the stored integer n represents
+// n*scale + offset; "bias" is accepted as another
+// name for "offset"). The stored integer is unsigned
+// unless the "signed" option is given. Values are
+// rounded to the nearest representable value; if
+// that value is out of range, or if the value is
+// a NaN or an infinity, Pack will panic.
+//
+//	type reading struct {
+//		Volts float32 `gopack:"12,fixed=4"`
+//		Temp  float64 `gopack:"10,scale=0.05,offset=-40"`
+//		Delta float64 `gopack:"8,fixed=2,signed"`
+//	}
+//
+// The tag on an array field applies to each of its
+// elements, so the following type takes up 4 bytes.
+//
+//	type mixer struct {
+//		Channels [8]uint8 `gopack:"4"`
+//	}
+//
+// Slice fields hold a variable number of elements.
+// The number is either packed before the elements,
+// as an unsigned integer of the width given by the
+// "len" option, or held in the earlier integer field
+// of the same struct named by the "lenfield" option,
+// in which case Pack will panic if that field does
+// not hold the length of the slice. As for arrays,
+// the rest of the tag applies to each element.
+// Unpack reuses the slice's storage if it has
+// enough capacity, and allocates it otherwise.
+//
+//	type record struct {
+//		Count uint8   `gopack:"4"`
+//		Items []entry `gopack:"lenfield=Count"`
+//		Notes []uint8 `gopack:"7,len=5"`
+//	}
+//
+// string and []byte fields are packed as a
+// sequence of characters. With the "bytes=<n>"
+// option, there are always n characters, and
+// shorter values are padded with NUL characters,
+// or with spaces if the "fill=space" option is
+// given (trailing fill characters are removed by
+// Unpack). Otherwise, the "len" or "lenfield"
+// option gives the length, as for slices. Each
+// character takes 8 bits, unless the "charset"
+// option restricts it to 7-bit ASCII ("ascii7")
+// or DEC SIXBIT ("sixbit", which covers space
+// through underscore, and is padded with spaces).
+// If a value holds a character outside its
+// charset, or is longer than its fixed length,
+// Pack will panic.
+//
+//	type station struct {
+//		ID   string `gopack:"bytes=8,fill=space"`
+//		Call string `gopack:"bytes=6,charset=sixbit"`
+//		Note []byte `gopack:"len=5,charset=ascii7"`
+//	}
+//
+// Pointer fields are packed as the value they
+// point to, and Unpack allocates that value if
+// the pointer is nil. Pack will panic if the
+// pointer is nil, unless the "optional" option
+// is given, in which case the value is preceded
+// by a presence bit, and a nil pointer is packed
+// as that bit alone. The rest of the tag applies
+// to the value pointed to. Recursive types, such
+// as a struct holding a pointer to its own type,
+// cannot be packed.
+//
+//	type message struct {
+//		ID  uint16
+//		Ext *extension `gopack:"optional"`
+//	}
+//
+// A field with the "if=<name>" option is packed
+// only if the earlier bool field of the same struct
+// named by the option is true. Otherwise, the field
+// takes up no space, is ignored by Pack, and is set
+// to its zero value by Unpack.
+//
+//	type frame struct {
+//		HasExt bool
+//		Length uint16
+//		Ext    extension `gopack:"if=HasExt"`
+//	}
+//
+// Interface fields are unions, which hold one of
+// several variant types registered for the interface
+// type with RegisterVariant, each under a key. The
+// "switch=<name>" option names the earlier integer
+// field of the same struct which holds the key of
+// the variant, and the variant is packed in place
+// of the field. Pack will panic if the field is nil,
+// holds an unregistered type, or holds a variant
+// with a different key, and Unpack will panic if
+// the key has no variant.
+//
+//	type Payload interface{}
+//
+//	type packet struct {
+//		Kind uint8   `gopack:"4"`
+//		Body Payload `gopack:"switch=Kind"`
+//	}
+//
+//	func init() {
+//		gopack.RegisterVariantT[Payload](1, Ping{})
+//		gopack.RegisterVariantT[Payload](2, Data{})
+//	}
+//
+// The packed size of a struct with slice fields
+// (or strings with a variable length, optional
+// pointers, conditional fields, or unions)
+// depends on its value (see PackedSizeof). Such
+// structs cannot be passed to LayoutOf, Gaps,
+// PackSlice, or Decoder.Decode, and fields after
+// a slice cannot be placed with "offset".
+//
 // If there are bits in the last used byte of b which
 // are beyond the end of the packed data (for example,
 // the last four bits of the second byte when packing
@@ -76,39 +241,161 @@ var unpackerCache struct {
 //		name string
 //		Age, Height uint8
 //	}
+//
+// The exception is blank (_) fields with a tag of the
+// form "pad=<bits>", which reserve the given number of
+// bits. Padding bits are packed as zero, or as the value
+// given by the "value" option. If the "check" option is
+// given, Unpack will panic if the padding bits do not
+// hold that value.
+//
+//	type header struct {
+//		Version uint8    `gopack:"4"`
+//		_       struct{} `gopack:"pad=3,check"`
+//		Urgent  bool
+//	}
+//
+// Fields are normally laid out one after another
+// in the order in which they are declared. A field
+// tagged with "offset=<bit>" is instead placed at
+// that bit offset from the start of its enclosing
+// struct, and the fields which follow it are laid
+// out after it. The width may be given alongside
+// as "width=<bits>". On a float field, or an array,
+// slice, or pointer of floats, "offset" is the
+// offset of a scaled value (see above) unless the
+// width is given as "width=<bits>", so such fields
+// are placed with both options. Bits which are not
+// covered by any field are packed as zero (see
+// Gaps), and if two fields overlap, Pack will panic.
+//
+//	type register struct {
+//		Enable bool   `gopack:"offset=0"`
+//		Mode   uint8  `gopack:"offset=4,width=3"`
+//		Count  uint16 `gopack:"offset=17,width=5"`
+//	}
+//
+// A field whose type implements BitPacker and
+// BitUnpacker controls its own encoding, and
+// occupies the number of bits given by its
+// BitWidth method.
+//
+// Types for which the gopackgen command has
+// generated methods (see Packer) are packed
+// without the use of reflection.
+//
+// Pack lays out fields in LSBFirst order. To
+// use a different order, see Config. In either
+// order, the packed bytes are the same on every
+// host, regardless of its byte order or its
+// alignment requirements.
 func Pack(b []byte, strct interface{}) {
-	v := reflect.ValueOf(strct)
-	p, bytes := packerFor(v)
+	Config{}.Pack(b, strct)
+}
+
+// PackE is like Pack, except that instead of
+// panicking, it returns any error encountered.
+// All errors returned are of type Error.
+//
+// If PackE returns an error because a field
+// holds a value which cannot be packed, the
+// contents of b are unspecified.
+func PackE(b []byte, strct interface{}) error {
+	return Config{}.PackE(b, strct)
+}
+
+// PackedSizeof returns the number of bytes needed to pack the given value.
+// For types with slice fields, variable-length strings, optional
+// pointers, conditional fields, or unions, this depends on their
+// values; for all other types, it depends only on the type.
+// If the type of strct cannot be packed, PackedSizeof will panic.
+func PackedSizeof(strct interface{}) int {
+	return Config{}.PackedSizeof(strct)
+}
+
+// AppendPack packs strct, which is subject to the
+// same restrictions as the argument to Pack, onto
+// the end of dst and returns the extended slice.
+// Unlike Pack, it grows dst if it is not large
+// enough, so that a header may be packed onto
+// the front of a packet, for example:
+//
+//	b := AppendPack(make([]byte, 0, 512), hdr)
+//	b = append(b, payload...)
+//
+// If strct cannot be packed, AppendPack will panic.
+func AppendPack(dst []byte, strct interface{}) []byte {
+	return Config{}.AppendPack(dst, strct)
+}
+
+// AppendPackE is like AppendPack, except that instead
+// of panicking, it returns any error encountered, along
+// with dst unchanged. All errors returned are of type
+// Error.
+func AppendPackE(dst []byte, strct interface{}) ([]byte, error) {
+	return Config{}.AppendPackE(dst, strct)
+}
+
+func appendPackE(order BitOrder, dst []byte, strct interface{}) ([]byte, error) {
+	c := packerFor(reflect.TypeOf(strct), order)
+	if c.err != nil {
+		return dst, c.err
+	}
+	n := len(dst)
+	_, bytes := c.sizeof(reflect.ValueOf(strct))
+	b := growZero(dst, bytes)
+	if err := packCached(order, c, b[n:], strct); err != nil {
+		return dst, err
+	}
+	return b, nil
+}
+
+func packE(order BitOrder, b []byte, strct interface{}) error {
+	if p, ok := strct.(Packer); ok && generatedFor(p, reflect.TypeOf(strct), order) {
+		return p.PackBits(b)
+	}
+	return packValue(order, b, reflect.ValueOf(strct))
+}
+
+func packValue(order BitOrder, b []byte, v reflect.Value) error {
+	c := packerFor(v.Type(), order)
+	if c.err != nil {
+		return c.err
+	}
+	_, bytes := c.sizeof(v)
 	if len(b) < bytes {
-		panic(Error{fmt.Errorf("gopack: buffer too small (%v; need %v)", len(b), bytes)})
+		return newError(ShortBuffer, "buffer too small (%v; need %v)", len(b), bytes)
 	}
 	for i := 0; i < bytes; i++ {
 		b[i] = 0
 	}
-	p(b, v)
+	return c.packer(b, v)
 }
 
-// PackedSizeof returns the number of bytes needed to pack the given value.
-func PackedSizeof(strct interface{}) int {
-	_, bytes := packerFor(reflect.ValueOf(strct))
-	return bytes
-}
-
-// Returns the packer and number of bytes needed by this packer.
-func packerFor(v reflect.Value) (packer, int) {
-	typ := v.Type()
-	packerCache.RLock()
-	entry, ok := packerCache.m[typ]
-	packerCache.RUnlock()
-	if ok {
-		return entry.packer, entry.bytes
+// Pack strct into b, which holds enough zero
+// bytes (see cachedPacker.sizeof), where c is
+// the cached packer for the type of strct.
+func packCached(order BitOrder, c cachedPacker, b []byte, strct interface{}) error {
+	if p, ok := strct.(Packer); ok && generatedFor(p, reflect.TypeOf(strct), order) {
+		return p.PackBits(b)
 	}
+	return c.packer(b, reflect.ValueOf(strct))
+}
 
-	p, bytes := makePackerWrapper(typ)
-	packerCache.Lock()
-	packerCache.m[typ] = cachedPacker{packer: p, bytes: bytes}
-	packerCache.Unlock()
-	return p, bytes
+// Returns the packer along with the number of bits
+// and bytes it packs, or the error encountered
+// while constructing the packer.
+func packerFor(typ reflect.Type, order BitOrder) cachedPacker {
+	key := cacheKey{typ, order}
+	v, ok := packerCache.Load(key)
+	if !ok {
+		v, _ = packerCache.LoadOrStore(key, new(packerEntry))
+	}
+	entry := v.(*packerEntry)
+	entry.once.Do(func() {
+		entry.cachedPacker = makeCachedPacker(typ, order)
+	})
+	return entry.cachedPacker
 }
 
 // Unpack the data in b into the fields of strct.
@@ -124,24 +411,43 @@ func packerFor(v reflect.Value) (packer, int) {
 // If b is not sufficiently long to hold all of
 // the bits of strct, Unpack will panic.
 func Unpack(b []byte, strct interface{}) {
-	v := reflect.ValueOf(strct)
-	typ := v.Type()
-	unpackerCache.RLock()
-	u, ok := unpackerCache.m[typ]
-	unpackerCache.RUnlock()
-	if ok {
-		u(b, v)
-		return
+	Config{}.Unpack(b, strct)
+}
+
+// UnpackE is like Unpack, except that instead of
+// panicking, it returns any error encountered.
+// All errors returned are of type Error.
+func UnpackE(b []byte, strct interface{}) error {
+	return Config{}.UnpackE(b, strct)
+}
+
+func unpackE(order BitOrder, b []byte, strct interface{}) error {
+	if u, ok := strct.(Unpacker); ok && generatedFor(u, reflect.TypeOf(strct), order) {
+		return u.UnpackBits(b)
 	}
+	return unpackValue(order, b, reflect.ValueOf(strct))
+}
 
-	u = makeUnpackerWrapper(typ)
-	unpackerCache.Lock()
-	unpackerCache.m[typ] = u
-	unpackerCache.Unlock()
-	u(b, v)
+func unpackValue(order BitOrder, b []byte, v reflect.Value) error {
+	c := unpackerFor(v.Type(), order)
+	if c.err != nil {
+		return c.err
+	}
+	return c.unpacker(b, v)
 }
 
-func init() {
-	packerCache.m = make(map[reflect.Type]cachedPacker)
-	unpackerCache.m = make(map[reflect.Type]unpacker)
+// Returns the unpacker along with the number of
+// bits and bytes it unpacks, or the error
+// encountered while constructing it.
+func unpackerFor(typ reflect.Type, order BitOrder) cachedUnpacker {
+	key := cacheKey{typ, order}
+	v, ok := unpackerCache.Load(key)
+	if !ok {
+		v, _ = unpackerCache.LoadOrStore(key, new(unpackerEntry))
+	}
+	entry := v.(*unpackerEntry)
+	entry.once.Do(func() {
+		entry.cachedUnpacker = makeCachedUnpacker(typ, order)
+	})
+	return entry.cachedUnpacker
 }
@@ -5,6 +5,9 @@
 package gopack
 
 import (
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -51,3 +54,149 @@ func TestLargeValue(t *testing.T) {
 		t.Fatalf("Expected %#v; got %#v", b, b2)
 	}
 }
+
+func TestPackEUnpackE(t *testing.T) {
+	type typ struct {
+		F1 uint8 `gopack:"4"`
+		F2 uint8 `gopack:"4"`
+	}
+
+	b := make([]byte, 1)
+	if err := PackE(b, typ{3, 12}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var val typ
+	if err := UnpackE(b, &val); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val != (typ{3, 12}) {
+		t.Fatalf("Expected %v; got %v", typ{3, 12}, val)
+	}
+
+	if err := PackE(b, typ{16, 0}); err == nil {
+		t.Fatalf("Expected error packing out-of-range value")
+	} else if _, ok := err.(Error); !ok {
+		t.Fatalf("Expected error of type Error; got %T", err)
+	}
+	if err := PackE(nil, typ{}); err == nil {
+		t.Fatalf("Expected error packing into short buffer")
+	}
+	if err := UnpackE(nil, &val); err == nil {
+		t.Fatalf("Expected error unpacking from short buffer")
+	}
+	if err := PackE(b, 0); err == nil {
+		t.Fatalf("Expected error packing non-struct type")
+	}
+	if err := UnpackE(b, 0); err == nil {
+		t.Fatalf("Expected error unpacking into non-struct type")
+	}
+}
+
+func TestAppendPack(t *testing.T) {
+	type header struct {
+		Version uint8 `gopack:"4"`
+		Length  uint16
+	}
+
+	b := AppendPack([]byte{0xAA}, header{3, 0x1234})
+	b = append(b, 0xBB)
+	expect := []byte{0xAA, 0x43, 0x23, 0x01, 0xBB}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	// With spare capacity, the bytes are
+	// packed in place, zeroing any junk
+	dst := []byte{1, 0xFF, 0xFF, 0xFF}[:1]
+	b = Config{Order: MSBFirst}.AppendPack(dst, header{3, 0x1234})
+	expect = []byte{1, 0x31, 0x23, 0x40}
+	if string(b) != string(expect) || &b[0] != &dst[0] {
+		t.Fatalf("Expected %v in place; got %v", expect, b)
+	}
+
+	b, err := AppendPackE(dst, header{16, 0})
+	if _, ok := err.(Error); !ok || len(b) != 1 {
+		t.Fatalf("Expected error of type Error and dst; got %v, %v", err, b)
+	}
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
+		AppendPack(nil, 0)
+	})
+}
+
+// Counts the number of times the
+// packer for its type is constructed
+type counted uint8
+
+var countedWidthCalls int32
+
+func (counted) BitWidth() int {
+	atomic.AddInt32(&countedWidthCalls, 1)
+	return 8
+}
+
+func (c counted) PackBitsAt(dst []byte, off int, o BitOrder) error {
+	o.PutBits(dst, off, 8, uint64(c))
+	return nil
+}
+
+func (c *counted) UnpackBitsAt(src []byte, off int, o BitOrder) error {
+	*c = counted(o.GetBits(src, off, 8))
+	return nil
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	type typ struct {
+		C counted
+	}
+	// Start cold, so that the first
+	// construction is measured
+	clearCaches()
+	before := atomic.LoadInt32(&countedWidthCalls)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, 1)
+			Pack(b, typ{42})
+			if b[0] != 42 {
+				t.Errorf("Expected 42; got %v", b[0])
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&countedWidthCalls) - before; n != 1 {
+		t.Errorf("Expected the packer to be constructed once; got %v", n)
+	}
+}
+
+// Packing and unpacking a type in either bit
+// order should compute its layout only once.
+func TestSharedPlan(t *testing.T) {
+	type typ struct {
+		A [2]counted
+		B uint8 `gopack:"3"`
+	}
+	clearCaches()
+	before := atomic.LoadInt32(&countedWidthCalls)
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		b := make([]byte, 3)
+		c.Pack(b, typ{[2]counted{1, 2}, 5})
+		var v typ
+		c.Unpack(b, &v)
+		if v != (typ{[2]counted{1, 2}, 5}) {
+			t.Errorf("%v: Unexpected value %v", order, v)
+		}
+		c.Pack(b, &v)
+	}
+	LayoutOf(typ{})
+	if n := atomic.LoadInt32(&countedWidthCalls) - before; n != 2 {
+		t.Errorf("Expected the layout to be computed once; got %v width calls", n)
+	}
+	p1, _ := planFor(reflect.TypeOf(typ{}))
+	p2, _ := planFor(reflect.TypeOf(&typ{}))
+	if p1 != p2 {
+		t.Errorf("Expected typ and *typ to share a plan")
+	}
+}
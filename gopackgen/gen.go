@@ -0,0 +1,699 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/synful/gopack"
+)
+
+const gopackPath = "github.com/synful/gopack"
+
+// Returns the source of a file containing methods
+// for the named types in the package in dir. The
+// file output is ignored when loading the package
+// since it may hold stale methods.
+func generate(dir, output string, names []string, order gopack.BitOrder) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+	g := generator{pkg: pkg, order: order, imports: make(map[string]string)}
+	for _, name := range names {
+		if err := g.generate(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"gopackgen -type=%s", strings.Join(names, ","))
+	if order == gopack.MSBFirst {
+		fmt.Fprintf(&buf, " -order=msb")
+	}
+	fmt.Fprintf(&buf, "\"; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.Name())
+	var paths []string
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if name := g.imports[path]; name != path[strings.LastIndex(path, "/")+1:] {
+			fmt.Fprintf(&buf, "%s ", name)
+		}
+		fmt.Fprintf(&buf, "%q\n", path)
+	}
+	fmt.Fprintf(&buf, "\n%q\n)\n", gopackPath)
+	buf.Write(g.buf.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("internal error: invalid generated code: %v", err)
+	}
+	return src, nil
+}
+
+func loadPackage(dir, output string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		path, err := filepath.Abs(filepath.Join(bp.Dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if path == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(bp.ImportPath, fset, files, nil)
+}
+
+type generator struct {
+	pkg   *types.Package
+	order gopack.BitOrder
+	// Names of the packages, by import path,
+	// used by the generated code other than
+	// gopack itself
+	imports map[string]string
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.buf, format, a...)
+}
+
+// A leaf is a field of a basic type,
+// or a padding field (in which case
+// typ is nil).
+type leaf struct {
+	gopack.FieldLayout
+	typ types.Type
+	tag reflect.StructTag
+}
+
+func (g *generator) generate(name string) error {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("no type %s in package %s", name, g.pkg.Name())
+	}
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok || isCustom(obj.Type()) {
+		return fmt.Errorf("%s is not a struct type without a custom encoding", name)
+	}
+	typ, err := mirror("", st)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	layout, err := layoutOf(typ)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	leaves := appendStructLeaves(nil, layout.Fields, st)
+
+	g.printf("\n// BitOrder returns the order in which PackBits\n")
+	g.printf("// and UnpackBits lay out the fields of %s.\n", name)
+	g.printf("func (%s) BitOrder() gopack.BitOrder { return gopack.%v }\n", name, g.order)
+	g.imports["reflect"] = "reflect"
+	g.printf("\n// PackedType returns the type for which PackBits\n")
+	g.printf("// and UnpackBits were generated.\n")
+	g.printf("func (%s) PackedType() reflect.Type { return reflect.TypeOf((*%s)(nil)).Elem() }\n", name, name)
+	g.generatePack(name, layout, leaves)
+	g.generateUnpack(name, layout, leaves)
+	return nil
+}
+
+// The reflect types which correspond to the
+// basic types which gopack can pack
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:    reflect.TypeOf(false),
+	types.Int:     reflect.TypeOf(int(0)),
+	types.Int8:    reflect.TypeOf(int8(0)),
+	types.Int16:   reflect.TypeOf(int16(0)),
+	types.Int32:   reflect.TypeOf(int32(0)),
+	types.Int64:   reflect.TypeOf(int64(0)),
+	types.Uint:    reflect.TypeOf(uint(0)),
+	types.Uint8:   reflect.TypeOf(uint8(0)),
+	types.Uint16:  reflect.TypeOf(uint16(0)),
+	types.Uint32:  reflect.TypeOf(uint32(0)),
+	types.Uint64:  reflect.TypeOf(uint64(0)),
+	types.Float32: reflect.TypeOf(float32(0)),
+	types.Float64: reflect.TypeOf(float64(0)),
+}
+
+// Returns a reflect type with the same layout as
+// typ, so that gopack itself can compute the layout.
+// Fields which gopack ignores are given empty types.
+func mirror(path string, typ types.Type) (reflect.Type, error) {
+	if isCustom(typ) {
+		return nil, fmt.Errorf("%s: fields with a custom encoding are not supported", path)
+	}
+	switch t := typ.Underlying().(type) {
+	case *types.Basic:
+		if rt, ok := basicTypes[t.Kind()]; ok {
+			return rt, nil
+		}
+	case *types.Array:
+		elem, err := mirror(path, t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(t.Len()), elem), nil
+	case *types.Struct:
+		var fields []reflect.StructField
+		for i := 0; i < t.NumFields(); i++ {
+			v := t.Field(i)
+			tag := reflect.StructTag(t.Tag(i))
+			field := reflect.StructField{Name: v.Name(), Tag: tag}
+			if v.Exported() {
+				ft, err := mirror(fieldPath(path, v.Name()), v.Type())
+				if err != nil {
+					return nil, err
+				}
+				field.Type = ft
+			} else {
+				field.PkgPath = "p"
+				field.Type = reflect.TypeOf(struct{}{})
+			}
+			fields = append(fields, field)
+		}
+		return reflect.StructOf(fields), nil
+	}
+	return nil, fmt.Errorf("%s: non-packable type %v", path, typ)
+}
+
+// Reports whether typ or a pointer to typ
+// implements gopack.BitPacker or BitUnpacker.
+func isCustom(typ types.Type) bool {
+	for _, t := range []types.Type{typ, types.NewPointer(typ)} {
+		ms := types.NewMethodSet(t)
+		if ms.Lookup(nil, "PackBitsAt") != nil || ms.Lookup(nil, "UnpackBitsAt") != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Returns the layout of typ, or the
+// error with which gopack panicked.
+func layoutOf(typ reflect.Type) (l gopack.Layout, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(gopack.Error)
+			if !ok {
+				panic(r)
+			}
+			err = e
+		}
+	}()
+	return gopack.LayoutOf(reflect.Zero(typ).Interface()), nil
+}
+
+func isPadding(v *types.Var, tag reflect.StructTag) bool {
+	return v.Name() == "_" && tag.Get("gopack") != ""
+}
+
+// Appends the leaves of the struct st, whose
+// fields have the layouts in fields, to ls.
+func appendStructLeaves(ls []leaf, fields []gopack.FieldLayout, st *types.Struct) []leaf {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		if isPadding(v, tag) {
+			ls = append(ls, leaf{fields[0], nil, tag})
+		} else if v.Exported() {
+			ls = appendLeaves(ls, fields[0], v.Type(), tag)
+		} else {
+			continue
+		}
+		fields = fields[1:]
+	}
+	return ls
+}
+
+func appendLeaves(ls []leaf, f gopack.FieldLayout, typ types.Type, tag reflect.StructTag) []leaf {
+	switch t := typ.Underlying().(type) {
+	case *types.Struct:
+		return appendStructLeaves(ls, f.Children, t)
+	case *types.Array:
+		// The tag applies to each element
+		for _, c := range f.Children {
+			ls = appendLeaves(ls, c, t.Elem(), tag)
+		}
+		return ls
+	}
+	return append(ls, leaf{f, typ, tag})
+}
+
+// Returns the name of typ as it
+// appears in the generated code.
+func (g *generator) typeName(typ types.Type) string {
+	return types.TypeString(typ, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+func (g *generator) generatePack(name string, l gopack.Layout, leaves []leaf) {
+	g.printf("\n// PackBits packs t into b without using reflection.\n")
+	g.printf("// It is equivalent to %s.\n", g.equivalent("PackE"))
+	g.printf("func (t %s) PackBits(b []byte) error {\n", name)
+	if l.Bytes > 0 {
+		g.checkLen(l.Bytes)
+		g.printf("for i := range b {\nb[i] = 0\n}\n")
+	}
+	if needsVar(leaves) {
+		g.printf("var u uint64\n")
+	}
+	if needsFloatVars(leaves) {
+		g.printf("var f, n float64\n")
+	}
+	for _, lf := range leaves {
+		x := "t." + lf.Path
+		switch {
+		case lf.typ == nil:
+			g.packPadding(lf)
+		case lf.GoType.Kind() == reflect.Bool:
+			op := byteOps(g.order, lf.BitOffset, 1)[0]
+			g.printf("if %s {\nb[%d] |= %#x\n}\n", x, op.i, op.apply(1))
+		case isFloat(lf.GoType):
+			g.packFloat(lf, x)
+		case lf.Signed:
+			g.packSigned(lf, x)
+		default:
+			g.packUnsigned(lf, x)
+		}
+	}
+	g.printf("return nil\n}\n")
+}
+
+func (g *generator) generateUnpack(name string, l gopack.Layout, leaves []leaf) {
+	g.printf("\n// UnpackBits unpacks b into t without using reflection.\n")
+	g.printf("// It is equivalent to %s.\n", g.equivalent("UnpackE"))
+	g.printf("func (t *%s) UnpackBits(b []byte) error {\n", name)
+	if l.Bytes > 0 {
+		g.checkLen(l.Bytes)
+	}
+	if needsVar(leaves) {
+		g.printf("var u uint64\n")
+	}
+	for _, lf := range leaves {
+		x := "t." + lf.Path
+		switch {
+		case lf.typ == nil:
+			g.unpackPadding(lf)
+		case lf.GoType.Kind() == reflect.Bool:
+			op := byteOps(g.order, lf.BitOffset, 1)[0]
+			g.printf("%s = b[%d]&%#x != 0\n", x, op.i, op.apply(1))
+		case isFloat(lf.GoType):
+			g.unpackFloat(lf, x)
+		default:
+			g.printf("u = %s\n", g.get(lf.BitOffset, lf.BitWidth))
+			if !lf.Signed {
+				g.assign(x, lf.typ, "u", "uint64")
+			} else if lf.BitWidth < 64 {
+				s := 64 - lf.BitWidth
+				g.assign(x, lf.typ, fmt.Sprintf("int64(u<<%d) >> %d", s, s), "int64")
+			} else {
+				g.assign(x, lf.typ, "int64(u)", "int64")
+			}
+		}
+	}
+	g.printf("return nil\n}\n")
+}
+
+// Returns the gopack function call
+// to which the generated method fn
+// is equivalent.
+func (g *generator) equivalent(fn string) string {
+	if g.order == gopack.LSBFirst {
+		return "gopack." + fn + "(b, t)"
+	}
+	return fmt.Sprintf("gopack.Config{Order: gopack.%v}.%s(b, t)", g.order, fn)
+}
+
+func (g *generator) checkLen(bytes int) {
+	g.printf("if len(b) < %d {\n", bytes)
+	g.fail("gopack.Error{Kind: gopack.ShortBuffer, ", "buffer too small (%v; need %v)", "len(b)", strconv.Itoa(bytes))
+	g.printf("}\nb = b[:%d]\n", bytes)
+}
+
+// Emits a return statement for an error of the
+// given kind concerning the field f.
+func (g *generator) fieldFail(kind string, f gopack.FieldLayout, format string, args ...string) {
+	prefix := fmt.Sprintf("gopack.Error{Kind: gopack.%s, Path: %q, Offset: %d, Width: %d,\n",
+		kind, f.Path, f.BitOffset, f.BitWidth)
+	g.fail(prefix, format, args...)
+}
+
+// prefix is the beginning of a gopack.Error
+// composite literal, up to the Err field.
+func (g *generator) fail(prefix, format string, args ...string) {
+	g.imports["fmt"] = "fmt"
+	g.printf("return %sErr: fmt.Errorf(%q, %s)}\n", prefix, format, strings.Join(args, ", "))
+}
+
+// Reports whether any leaf needs the
+// variable u. Padding uses its own.
+func needsVar(leaves []leaf) bool {
+	for _, lf := range leaves {
+		if lf.typ != nil && lf.GoType.Kind() != reflect.Bool {
+			return true
+		}
+	}
+	return false
+}
+
+func needsFloatVars(leaves []leaf) bool {
+	for _, lf := range leaves {
+		if lf.typ != nil && isFloat(lf.GoType) && getFloatEncoding(lf).quantized {
+			return true
+		}
+	}
+	return false
+}
+
+func isFloat(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64
+}
+
+func (g *generator) packUnsigned(lf leaf, x string) {
+	if lf.BitWidth < uint64(lf.GoType.Bits()) {
+		max := uint64(math.MaxUint64) >> (64 - lf.BitWidth)
+		g.printf("if %s > %d {\n", x, max)
+		g.fieldFail("Overflow", lf.FieldLayout, "value out of range: max %v; got %v",
+			fmt.Sprintf("uint64(%d)", max), "uint64("+x+")")
+		g.printf("}\n")
+	}
+	g.printf("u = uint64(%s)\n", x)
+	g.put("u", lf.BitOffset, lf.BitWidth)
+}
+
+func (g *generator) packSigned(lf leaf, x string) {
+	if lf.BitWidth < uint64(lf.GoType.Bits()) {
+		min := int64(-1) << (lf.BitWidth - 1)
+		max := int64(uint64(math.MaxUint64) >> (65 - lf.BitWidth))
+		g.printf("if v := int64(%s); v < %d || v > %d {\n", x, min, max)
+		g.fieldFail("Overflow", lf.FieldLayout, "value out of range: max %v, min %v; got %v",
+			fmt.Sprintf("int64(%d)", max), fmt.Sprintf("int64(%d)", min), "v")
+		g.printf("}\n")
+	}
+	g.printf("u = uint64(%s)%s\n", x, mask(lf.BitWidth))
+	g.put("u", lf.BitOffset, lf.BitWidth)
+}
+
+// Returns an expression which masks a
+// value to width bits, if necessary.
+func mask(width uint64) string {
+	if width >= 64 {
+		return ""
+	}
+	return fmt.Sprintf(" & %#x", (uint64(1)<<width)-1)
+}
+
+// floatEncoding mirrors the encoding gopack
+// uses for a float field. Its tag has already
+// been validated by gopack.LayoutOf.
+type floatEncoding struct {
+	quantized, signed bool
+	scale, bias       float64
+}
+
+func getFloatEncoding(lf leaf) floatEncoding {
+	enc := floatEncoding{scale: 1}
+	parts := strings.Split(lf.tag.Get("gopack"), ",")
+	// Without a "width=" part, "offset"
+	// is the value offset of the float
+	// rather than its placement
+	bias := "offset"
+	for _, part := range parts {
+		if strings.HasPrefix(part, "width=") {
+			bias = "bias"
+		}
+	}
+	for _, part := range parts {
+		kv := strings.SplitN(part, "=", 2)
+		if kv[0] == bias {
+			kv[0] = "bias"
+		}
+		switch kv[0] {
+		case "fixed":
+			frac, _ := strconv.Atoi(kv[1])
+			enc.quantized = true
+			enc.scale = math.Ldexp(1, -frac)
+		case "scale":
+			enc.quantized = true
+			enc.scale, _ = strconv.ParseFloat(kv[1], 64)
+		case "bias":
+			enc.quantized = true
+			enc.bias, _ = strconv.ParseFloat(kv[1], 64)
+		case "signed":
+			enc.signed = true
+		}
+	}
+	return enc
+}
+
+func (enc floatEncoding) value(n float64) float64 {
+	return float64(n*enc.scale) + enc.bias
+}
+
+// Formats f as a Go constant which
+// converts exactly to f.
+func float(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (g *generator) packFloat(lf leaf, x string) {
+	g.imports["math"] = "math"
+	enc := getFloatEncoding(lf)
+	if !enc.quantized {
+		if lf.GoType.Kind() == reflect.Float32 {
+			g.printf("u = uint64(math.Float32bits(float32(float64(%s))))\n", x)
+		} else {
+			g.printf("u = math.Float64bits(float64(%s))\n", x)
+		}
+		g.put("u", lf.BitOffset, lf.BitWidth)
+		return
+	}
+
+	min, max := 0.0, math.Ldexp(1, int(lf.BitWidth))
+	if enc.signed {
+		max = math.Ldexp(1, int(lf.BitWidth)-1)
+		min = -max
+	}
+	g.printf("f = float64(%s)\n", x)
+	g.printf("if math.IsNaN(f) || math.IsInf(f, 0) {\n")
+	g.fieldFail("Unrepresentable", lf.FieldLayout, "value not representable: %v", "f")
+	g.printf("}\n")
+	g.printf("n = math.Floor((f - %s) / %s + 0.5)\n", float(enc.bias), float(enc.scale))
+	g.printf("if n < %s || n >= %s {\n", float(min), float(max))
+	g.fieldFail("Overflow", lf.FieldLayout, "value out of range: max %v, min %v; got %v",
+		"float64("+float(enc.value(max-1))+")", "float64("+float(enc.value(min))+")", "f")
+	g.printf("}\n")
+	if enc.signed {
+		g.printf("u = uint64(int64(n))%s\n", mask(lf.BitWidth))
+	} else {
+		g.printf("u = uint64(n)\n")
+	}
+	g.put("u", lf.BitOffset, lf.BitWidth)
+}
+
+func (g *generator) unpackFloat(lf leaf, x string) {
+	enc := getFloatEncoding(lf)
+	g.printf("u = %s\n", g.get(lf.BitOffset, lf.BitWidth))
+	var val string
+	switch {
+	case !enc.quantized && lf.GoType.Kind() == reflect.Float32:
+		g.imports["math"] = "math"
+		val = "float64(math.Float32frombits(uint32(u)))"
+	case !enc.quantized:
+		g.imports["math"] = "math"
+		val = "math.Float64frombits(u)"
+	default:
+		n := "float64(u)"
+		if enc.signed {
+			n = "float64(int64(u))"
+			if lf.BitWidth < 64 {
+				s := 64 - lf.BitWidth
+				n = fmt.Sprintf("float64(int64(u<<%d) >> %d)", s, s)
+			}
+		}
+		val = fmt.Sprintf("float64(%s*%s) + %s", n, float(enc.scale), float(enc.bias))
+	}
+	g.assign(x, lf.typ, val, "float64")
+}
+
+// Emits an assignment to x, of type typ, of
+// the expression expr, whose type is named from.
+func (g *generator) assign(x string, typ types.Type, expr, from string) {
+	if name := g.typeName(typ); name != from {
+		expr = name + "(" + expr + ")"
+	}
+	g.printf("%s = %s\n", x, expr)
+}
+
+func (g *generator) packPadding(lf leaf) {
+	value, _ := strconv.ParseUint(paddingOption(lf.tag, "value"), 0, 64)
+	if value == 0 {
+		return
+	}
+	for _, op := range byteOps(g.order, lf.BitOffset, lf.BitWidth) {
+		if b := op.apply(value); b != 0 {
+			g.printf("b[%d] |= %#x\n", op.i, b)
+		}
+	}
+}
+
+func (g *generator) unpackPadding(lf leaf) {
+	tag := lf.tag.Get("gopack")
+	if !strings.Contains(","+tag+",", ",check,") {
+		return
+	}
+	value, _ := strconv.ParseUint(paddingOption(lf.tag, "value"), 0, 64)
+	// Padding wider than 64 bits is always
+	// zero, so check it 64 bits at a time
+	for off := uint64(0); off < lf.BitWidth; off += 64 {
+		width := lf.BitWidth - off
+		if width > 64 {
+			width = 64
+		}
+		g.printf("if v := %s; v != %d {\n", g.get(lf.BitOffset+off, width), value)
+		g.fieldFail("BadPadding", lf.FieldLayout, "padding has unexpected value: expected %v; got %v",
+			fmt.Sprintf("uint64(%d)", value), "v")
+		g.printf("}\n")
+	}
+}
+
+// Returns the value of the option key in
+// tag, or "0" if there is no such option.
+func paddingOption(tag reflect.StructTag, key string) string {
+	for _, part := range strings.Split(tag.Get("gopack"), ",") {
+		if strings.HasPrefix(part, key+"=") {
+			return part[len(key)+1:]
+		}
+	}
+	return "0"
+}
+
+// A byteOp describes the part of a value which
+// is stored in a single byte: b[i] holds the
+// value shifted left by shl or right by shr.
+type byteOp struct {
+	i        uint64
+	shl, shr uint64
+}
+
+func (op byteOp) apply(u uint64) byte {
+	return byte(u << op.shl >> op.shr)
+}
+
+// Returns the bytes which hold the width
+// bits at off, in the same order as the
+// functions in gopack's bits.go visit them.
+func byteOps(order gopack.BitOrder, off, width uint64) []byteOp {
+	if order == gopack.MSBFirst {
+		end := off + width
+		i := (end - 1) / 8
+		pad := (8 - end%8) % 8
+		ops := []byteOp{{i: i, shl: pad}}
+		shr := 8 - pad
+		for n := int(width+pad) - 8; n > 0; n -= 8 {
+			i--
+			ops = append(ops, byteOp{i: i, shr: shr})
+			shr += 8
+		}
+		return ops
+	}
+
+	i := off / 8
+	lsb := off % 8
+	ops := []byteOp{{i: i, shl: lsb}}
+	shr := 8 - lsb
+	for n := int(lsb+width) - 8; n > 0; n -= 8 {
+		i++
+		ops = append(ops, byteOp{i: i, shr: shr})
+		shr += 8
+	}
+	return ops
+}
+
+// Emits statements which OR the width low
+// bits of the uint64 variable u into b at off.
+func (g *generator) put(u string, off, width uint64) {
+	for _, op := range byteOps(g.order, off, width) {
+		switch {
+		case op.shl > 0:
+			g.printf("b[%d] |= byte(%s << %d)\n", op.i, u, op.shl)
+		case op.shr > 0:
+			g.printf("b[%d] |= byte(%s >> %d)\n", op.i, u, op.shr)
+		default:
+			g.printf("b[%d] |= byte(%s)\n", op.i, u)
+		}
+	}
+}
+
+// Returns an expression for the
+// width bits of b at off.
+func (g *generator) get(off, width uint64) string {
+	var terms []string
+	var got uint64
+	for _, op := range byteOps(g.order, off, width) {
+		switch {
+		case op.shl > 0:
+			terms = append(terms, fmt.Sprintf("uint64(b[%d])>>%d", op.i, op.shl))
+		case op.shr > 0:
+			terms = append(terms, fmt.Sprintf("uint64(b[%d])<<%d", op.i, op.shr))
+		default:
+			terms = append(terms, fmt.Sprintf("uint64(b[%d])", op.i))
+		}
+		got += 8 - op.shl
+	}
+	expr := strings.Join(terms, " | ")
+	if width < 64 && got > width {
+		if len(terms) > 1 {
+			expr = "(" + expr + ")"
+		}
+		expr += mask(width)
+	}
+	return expr
+}
@@ -0,0 +1,63 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/synful/gopack"
+)
+
+// The tests in the testtypes package check the
+// generated methods against gopack itself; this
+// checks that the methods are up to date.
+func TestGenerateTestTypes(t *testing.T) {
+	for _, c := range []struct {
+		output string
+		names  string
+		order  gopack.BitOrder
+	}{
+		{"unsigned_gopack.go", "unsigned,signed,mixed,floats,padded,placed,Header", gopack.LSBFirst},
+		{"network_gopack.go", "network", gopack.MSBFirst},
+	} {
+		output := filepath.Join("testtypes", c.output)
+		src, err := generate("testtypes", output, strings.Split(c.names, ","), c.order)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		old, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(src) != string(old) {
+			t.Errorf("%s is out of date; run go generate in testtypes", output)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	pkg, err := loadPackage("testtypes", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, c := range []struct {
+		name, err string
+	}{
+		{"nosuch", "no type nosuch in package testtypes"},
+		{"level", "level is not a struct type without a custom encoding"},
+		{"withCustom", "withCustom: Stamp: fields with a custom encoding are not supported"},
+		{"unsupported", "unsupported: Ptr: non-packable type *uint8"},
+		{"tooWide", "tooWide: gopack: A: struct tag too wide for type uint8 (9)"},
+	} {
+		g := generator{pkg: pkg, imports: make(map[string]string)}
+		err := g.generate(c.name)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("Expected error %q; got %v", c.err, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Gopackgen generates methods which pack and unpack
+// struct types without the use of reflection. Given
+// the name of a struct type T which gopack can pack,
+// gopackgen writes the methods
+//
+//	func (T) BitOrder() gopack.BitOrder
+//	func (T) PackedType() reflect.Type
+//	func (t T) PackBits(b []byte) error
+//	func (t *T) UnpackBits(b []byte) error
+//
+// which implement gopack.Packer and gopack.Unpacker.
+// gopack.Pack and gopack.Unpack call these methods
+// in place of their reflection-based implementations,
+// and the packed bytes and any errors are identical.
+//
+// Gopackgen is intended to be run by go generate:
+//
+//	//go:generate gopackgen -type=header
+//
+// Usage:
+//
+//	gopackgen -type=T[,T...] [-order=lsb|msb] [-output=file] [dir]
+//
+// The types must be declared in the package in dir
+// (by default, the current directory). The methods
+// are written to the file given by -output, which
+// defaults to <t>_gopack.go in dir, where t is the
+// lower-cased name of the first type. The generated
+// methods must be regenerated whenever the types
+// (or any types they contain) change.
+//
+// Only fixed-size fields are supported. Gopackgen
+// rejects types with slice, string, pointer, or
+// interface (union) fields, conditional fields
+// (tagged with "if"), and fields whose types
+// implement gopack.BitPacker.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/synful/gopack"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of type names; must be set")
+	order     = flag.String("order", "lsb", "bit order: lsb or msb")
+	output    = flag.String("output", "", "output file name; default srcdir/<type>_gopack.go")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gopackgen -type=T[,T...] [-order=lsb|msb] [-output=file] [dir]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gopackgen: ")
+	flag.Usage = usage
+	flag.Parse()
+	if *typeNames == "" || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	names := strings.Split(*typeNames, ",")
+
+	var ord gopack.BitOrder
+	switch *order {
+	case "lsb":
+		ord = gopack.LSBFirst
+	case "msb":
+		ord = gopack.MSBFirst
+	default:
+		log.Fatalf("unknown bit order %q", *order)
+	}
+
+	dir := "."
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, strings.ToLower(names[0])+"_gopack.go")
+	}
+
+	src, err := generate(dir, out, names, ord)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
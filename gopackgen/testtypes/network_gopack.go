@@ -0,0 +1,162 @@
+// Code generated by "gopackgen -type=network -order=msb"; DO NOT EDIT.
+
+package testtypes
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/synful/gopack"
+)
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of network.
+func (network) BitOrder() gopack.BitOrder { return gopack.MSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (network) PackedType() reflect.Type { return reflect.TypeOf((*network)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.Config{Order: gopack.MSBFirst}.PackE(b, t).
+func (t network) PackBits(b []byte) error {
+	if len(b) < 11 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 11)}
+	}
+	b = b[:11]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	var f, n float64
+	if t.Version > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Version", Offset: 0, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(15), uint64(t.Version))}
+	}
+	u = uint64(t.Version)
+	b[0] |= byte(u << 4)
+	if t.IHL > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "IHL", Offset: 4, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(15), uint64(t.IHL))}
+	}
+	u = uint64(t.IHL)
+	b[0] |= byte(u)
+	if t.DSCP > 63 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "DSCP", Offset: 8, Width: 6,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(63), uint64(t.DSCP))}
+	}
+	u = uint64(t.DSCP)
+	b[1] |= byte(u << 2)
+	if t.ECN > 3 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "ECN", Offset: 14, Width: 2,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(3), uint64(t.ECN))}
+	}
+	u = uint64(t.ECN)
+	b[1] |= byte(u)
+	u = uint64(t.Length)
+	b[3] |= byte(u)
+	b[2] |= byte(u >> 8)
+	b[4] |= 0xa0
+	if t.Offset > 8191 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Offset", Offset: 35, Width: 13,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(8191), uint64(t.Offset))}
+	}
+	u = uint64(t.Offset)
+	b[5] |= byte(u)
+	b[4] |= byte(u >> 8)
+	if v := int64(t.Skew); v < -1024 || v > 1023 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Skew", Offset: 48, Width: 11,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(1023), int64(-1024), v)}
+	}
+	u = uint64(t.Skew) & 0x7ff
+	b[7] |= byte(u << 5)
+	b[6] |= byte(u >> 3)
+	f = float64(t.Gain)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Gain", Offset: 59, Width: 9,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f-0)/0.125 + 0.5)
+	if n < -256 || n >= 256 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Gain", Offset: 59, Width: 9,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(31.875), float64(-32), f)}
+	}
+	u = uint64(int64(n)) & 0x1ff
+	b[8] |= byte(u << 4)
+	b[7] |= byte(u >> 4)
+	if t.Inner[0].A {
+		b[8] |= 0x8
+	}
+	if t.Inner[0].B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner[0].B", Offset: 69, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Inner[0].B))}
+	}
+	u = uint64(t.Inner[0].B)
+	b[9] |= byte(u << 6)
+	b[8] |= byte(u >> 2)
+	if t.Inner[0].Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner[0].Level", Offset: 74, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Inner[0].Level))}
+	}
+	u = uint64(t.Inner[0].Level)
+	b[9] |= byte(u << 3)
+	if t.Inner[1].A {
+		b[9] |= 0x4
+	}
+	if t.Inner[1].B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner[1].B", Offset: 78, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Inner[1].B))}
+	}
+	u = uint64(t.Inner[1].B)
+	b[10] |= byte(u << 5)
+	b[9] |= byte(u >> 3)
+	if t.Inner[1].Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner[1].Level", Offset: 83, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Inner[1].Level))}
+	}
+	u = uint64(t.Inner[1].Level)
+	b[10] |= byte(u << 2)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.Config{Order: gopack.MSBFirst}.UnpackE(b, t).
+func (t *network) UnpackBits(b []byte) error {
+	if len(b) < 11 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 11)}
+	}
+	b = b[:11]
+	var u uint64
+	u = uint64(b[0]) >> 4
+	t.Version = uint8(u)
+	u = uint64(b[0]) & 0xf
+	t.IHL = uint8(u)
+	u = uint64(b[1]) >> 2
+	t.DSCP = uint8(u)
+	u = uint64(b[1]) & 0x3
+	t.ECN = uint8(u)
+	u = uint64(b[3]) | uint64(b[2])<<8
+	t.Length = uint16(u)
+	if v := uint64(b[4]) >> 5; v != 5 {
+		return gopack.Error{Kind: gopack.BadPadding, Path: "_", Offset: 32, Width: 3,
+			Err: fmt.Errorf("padding has unexpected value: expected %v; got %v", uint64(5), v)}
+	}
+	u = (uint64(b[5]) | uint64(b[4])<<8) & 0x1fff
+	t.Offset = uint16(u)
+	u = uint64(b[7])>>5 | uint64(b[6])<<3
+	t.Skew = int16(int64(u<<53) >> 53)
+	u = (uint64(b[8])>>4 | uint64(b[7])<<4) & 0x1ff
+	t.Gain = float32(float64(float64(int64(u<<55)>>55)*0.125) + 0)
+	t.Inner[0].A = b[8]&0x8 != 0
+	u = (uint64(b[9])>>6 | uint64(b[8])<<2) & 0x1f
+	t.Inner[0].B = uint8(u)
+	u = uint64(b[9]) >> 3 & 0x7
+	t.Inner[0].Level = level(u)
+	t.Inner[1].A = b[9]&0x4 != 0
+	u = (uint64(b[10])>>5 | uint64(b[9])<<3) & 0x1f
+	t.Inner[1].B = uint8(u)
+	u = uint64(b[10]) >> 2 & 0x7
+	t.Inner[1].Level = level(u)
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testtypes holds types for which gopackgen
+// has generated methods, so that the tests can
+// check the methods against gopack's reflection-
+// based implementation.
+package testtypes
+
+import (
+	"time"
+
+	"github.com/synful/gopack"
+	"github.com/synful/gopack/testing"
+)
+
+//go:generate gopackgen -type=unsigned,signed,mixed,floats,padded,placed,Header
+//go:generate gopackgen -type=network -order=msb -output=network_gopack.go
+
+type level uint8
+
+type unsigned struct {
+	U1  uint8 `gopack:"1"`
+	U3  uint8 `gopack:"3"`
+	U8  uint8
+	U12 uint16 `gopack:"12"`
+	U16 uint16
+	U29 uint32 `gopack:"29"`
+	U57 uint64 `gopack:"57"`
+	U64 uint64
+	U   uint `gopack:"7"`
+}
+
+type signed struct {
+	I1  int8 `gopack:"1"`
+	I5  int8 `gopack:"5"`
+	I8  int8
+	I13 int16 `gopack:"13"`
+	I32 int32
+	I50 int64 `gopack:"50"`
+	I64 int64
+	I   int `gopack:"9"`
+}
+
+type inner struct {
+	A     bool
+	B     uint8 `gopack:"5"`
+	Level level `gopack:"3"`
+}
+
+type mixed struct {
+	Flag   bool
+	Month  time.Month `gopack:"4"`
+	Inner  inner
+	Array  [3]inner
+	Matrix [2][2]int8 `gopack:"3"`
+	Typ    testing.Typ
+	hidden string
+}
+
+type floats struct {
+	Raw32  float32
+	Raw64  float64
+	Volts  float32 `gopack:"12,fixed=4"`
+	Temp   float64 `gopack:"10,scale=0.05,bias=-40"`
+	Delta  float64 `gopack:"8,fixed=2,signed"`
+	Coarse float32 `gopack:"3,scale=100,signed"`
+	Wide   float64 `gopack:"64,fixed=8"`
+}
+
+type padded struct {
+	A uint8    `gopack:"3"`
+	_ struct{} `gopack:"pad=2,value=3"`
+	B uint8    `gopack:"4"`
+	_ struct{} `gopack:"pad=70,check"`
+	C bool
+	_ [0]byte `gopack:"pad=13,value=0x1a5c,check"`
+	_ struct{}
+}
+
+type placed struct {
+	Flag  bool   `gopack:"offset=31"`
+	Mode  uint8  `gopack:"offset=4,width=3"`
+	Count uint16 `gopack:"offset=17,width=5"`
+	Next  int8   `gopack:"2"`
+	Inner inner  `gopack:"offset=40"`
+}
+
+type network struct {
+	Version, IHL uint8 `gopack:"4"`
+	DSCP         uint8 `gopack:"6"`
+	ECN          uint8 `gopack:"2"`
+	Length       uint16
+	_            struct{} `gopack:"pad=3,value=5,check"`
+	Offset       uint16   `gopack:"13"`
+	Skew         int16    `gopack:"11"`
+	Gain         float32  `gopack:"9,fixed=3,signed"`
+	Inner        [2]inner
+}
+
+// Exported so that it may be embedded
+// in a packed struct
+type Header struct {
+	Version uint8 `gopack:"4"`
+	Flags   uint8 `gopack:"4"`
+}
+
+// Types for which gopackgen reports errors
+
+type unsupported struct {
+	Ptr *uint8
+}
+
+type tooWide struct {
+	A uint8 `gopack:"9"`
+}
+
+type stamp uint32
+
+func (stamp) BitWidth() int { return 20 }
+
+func (s stamp) PackBitsAt(dst []byte, bitOffset int, order gopack.BitOrder) error {
+	order.PutBits(dst, bitOffset, 20, uint64(s))
+	return nil
+}
+
+func (s *stamp) UnpackBitsAt(src []byte, bitOffset int, order gopack.BitOrder) error {
+	*s = stamp(order.GetBits(src, bitOffset, 20))
+	return nil
+}
+
+type withCustom struct {
+	Stamp stamp
+}
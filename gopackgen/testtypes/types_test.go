@@ -0,0 +1,253 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testtypes
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/synful/gopack"
+)
+
+var (
+	_ gopack.Packer   = unsigned{}
+	_ gopack.Unpacker = (*unsigned)(nil)
+	_ gopack.Packer   = network{}
+	_ gopack.Unpacker = (*network)(nil)
+)
+
+// Types with the same layout as the
+// generated types, but no methods, so
+// that gopack uses reflection to pack them
+type (
+	plainUnsigned unsigned
+	plainSigned   signed
+	plainMixed    mixed
+	plainFloats   floats
+	plainPadded   padded
+	plainPlaced   placed
+	plainNetwork  network
+)
+
+var cases = []struct {
+	name       string
+	gen, plain func() interface{}
+	order      gopack.BitOrder
+}{
+	{"unsigned", func() interface{} { return new(unsigned) }, func() interface{} { return new(plainUnsigned) }, gopack.LSBFirst},
+	{"signed", func() interface{} { return new(signed) }, func() interface{} { return new(plainSigned) }, gopack.LSBFirst},
+	{"mixed", func() interface{} { return new(mixed) }, func() interface{} { return new(plainMixed) }, gopack.LSBFirst},
+	{"floats", func() interface{} { return new(floats) }, func() interface{} { return new(plainFloats) }, gopack.LSBFirst},
+	{"padded", func() interface{} { return new(padded) }, func() interface{} { return new(plainPadded) }, gopack.LSBFirst},
+	{"placed", func() interface{} { return new(placed) }, func() interface{} { return new(plainPlaced) }, gopack.LSBFirst},
+	{"network", func() interface{} { return new(network) }, func() interface{} { return new(plainNetwork) }, gopack.MSBFirst},
+}
+
+// Unpack random bytes with both the generated
+// methods and reflection, and check that the
+// results agree, and that packing the results
+// agrees as well.
+func TestMatchesReflection(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, c := range cases {
+		cfg := gopack.Config{Order: c.order}
+		n := cfg.PackedSizeof(c.plain())
+		for i := 0; i < 1000; i++ {
+			b := make([]byte, n)
+			r.Read(b)
+			gen, plain := c.gen(), c.plain()
+			errGen := cfg.UnpackE(b, gen)
+			errPlain := cfg.UnpackE(b, plain)
+			if !reflect.DeepEqual(errGen, errPlain) {
+				t.Fatalf("%s: unpacking %v: got error %v; reflection got %v", c.name, b, errGen, errPlain)
+			}
+			valGen, valPlain := reflect.ValueOf(gen).Elem(), reflect.ValueOf(plain).Elem()
+			if fmt.Sprint(valGen) != fmt.Sprint(valPlain) {
+				t.Fatalf("%s: unpacking %v: got %v; reflection got %v", c.name, b, valGen, valPlain)
+			}
+			if errGen != nil {
+				continue
+			}
+			testPack(t, cfg, c.name, valGen.Interface(), valPlain.Interface())
+		}
+		testPack(t, cfg, c.name, reflect.ValueOf(c.gen()).Elem().Interface(), reflect.ValueOf(c.plain()).Elem().Interface())
+	}
+}
+
+func testPack(t *testing.T, cfg gopack.Config, name string, gen, plain interface{}) {
+	n := cfg.PackedSizeof(plain)
+	bGen, bPlain := make([]byte, n+1), make([]byte, n+1)
+	for i := range bGen {
+		bGen[i], bPlain[i] = 0xFF, 0xFF
+	}
+	errGen := cfg.PackE(bGen, gen)
+	errPlain := cfg.PackE(bPlain, plain)
+	if !reflect.DeepEqual(errGen, errPlain) {
+		t.Fatalf("%s: packing %v: got error %v; reflection got %v", name, gen, errGen, errPlain)
+	}
+	if errGen == nil && string(bGen) != string(bPlain) {
+		t.Fatalf("%s: packing %v: got %v; reflection got %v", name, gen, bGen, bPlain)
+	}
+}
+
+func TestErrorsMatchReflection(t *testing.T) {
+	lsb := gopack.Config{}
+	msb := gopack.Config{Order: gopack.MSBFirst}
+	testPack(t, lsb, "unsigned", unsigned{U3: 8}, plainUnsigned{U3: 8})
+	testPack(t, lsb, "unsigned", unsigned{U57: 1 << 57}, plainUnsigned{U57: 1 << 57})
+	testPack(t, lsb, "signed", signed{I5: -17}, plainSigned{I5: -17})
+	testPack(t, lsb, "signed", signed{I: 256}, plainSigned{I: 256})
+	testPack(t, lsb, "mixed", mixed{Month: time.December}, plainMixed{Month: time.December})
+	testPack(t, lsb, "mixed", mixed{Array: [3]inner{2: {Level: 8}}}, plainMixed{Array: [3]inner{2: {Level: 8}}})
+	testPack(t, lsb, "floats", floats{Volts: -1}, plainFloats{Volts: -1})
+	testPack(t, lsb, "floats", floats{Temp: math.NaN()}, plainFloats{Temp: math.NaN()})
+	testPack(t, lsb, "floats", floats{Coarse: 351}, plainFloats{Coarse: 351})
+	testPack(t, lsb, "floats", floats{Wide: math.Inf(-1)}, plainFloats{Wide: math.Inf(-1)})
+	testPack(t, msb, "network", network{Skew: -1025}, plainNetwork{Skew: -1025})
+	testPack(t, msb, "network", network{Gain: 32}, plainNetwork{Gain: 32})
+
+	// A buffer which is too short
+	errGen := gopack.UnpackE(make([]byte, 11), &floats{})
+	errPlain := gopack.UnpackE(make([]byte, 11), &plainFloats{})
+	if !reflect.DeepEqual(errGen, errPlain) {
+		t.Fatalf("Got error %v; reflection got %v", errGen, errPlain)
+	}
+	testPack(t, lsb, "floats", floats{}, plainFloats{})
+}
+
+// The generated methods are only used
+// in the order they were generated for.
+func TestOtherOrder(t *testing.T) {
+	msb := gopack.Config{Order: gopack.MSBFirst}
+	val := mixed{Flag: true, Month: time.March, Array: [3]inner{1: {true, 17, 5}}}
+	testPack(t, msb, "mixed", val, plainMixed(val))
+
+	b := []byte{0x81, 0x20, 0xFF, 0x00, 0x12, 0x34, 0x56, 0x78}
+	var gen mixed
+	var plain plainMixed
+	msb.Unpack(b, &gen)
+	msb.Unpack(b, &plain)
+	if plainMixed(gen) != plain {
+		t.Fatalf("Got %v; reflection got %v", gen, plain)
+	}
+}
+
+// PackSlice, UnpackSlice, and AppendPack
+// use the generated methods as well.
+func TestSlices(t *testing.T) {
+	val := mixed{Flag: true, Month: time.March, Array: [3]inner{1: {true, 17, 5}}}
+	if bGen, bPlain := gopack.AppendPack(nil, val), gopack.AppendPack(nil, plainMixed(val)); string(bGen) != string(bPlain) {
+		t.Fatalf("got %v; reflection got %v", bGen, bPlain)
+	}
+	gen := []mixed{val, {}, val}
+	plain := []plainMixed{plainMixed(val), {}, plainMixed(val)}
+	for _, contiguous := range []bool{false, true} {
+		cfg := gopack.Config{Contiguous: contiguous}
+		bGen := make([]byte, cfg.PackedSliceSizeof(gen))
+		bPlain := make([]byte, cfg.PackedSliceSizeof(plain))
+		cfg.PackSlice(bGen, gen)
+		cfg.PackSlice(bPlain, plain)
+		if string(bGen) != string(bPlain) {
+			t.Fatalf("%v: got %v; reflection got %v", cfg, bGen, bPlain)
+		}
+		gen2 := make([]mixed, 3)
+		cfg.UnpackSlice(bGen, gen2)
+		if !reflect.DeepEqual(gen, gen2) {
+			t.Fatalf("%v: expected %v; got %v", cfg, gen, gen2)
+		}
+	}
+}
+
+// Codecs use the generated methods
+// as well.
+func TestCodec(t *testing.T) {
+	val := mixed{Flag: true, Month: time.March, Array: [3]inner{1: {true, 17, 5}}}
+	gen, err := gopack.CodecFor(reflect.TypeOf(val))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plain, _ := gopack.CodecFor(reflect.TypeOf(plainMixed{}))
+	bGen, bPlain := make([]byte, gen.Size()), make([]byte, plain.Size())
+	gen.Pack(bGen, &val)
+	plain.Pack(bPlain, plainMixed(val))
+	if string(bGen) != string(bPlain) {
+		t.Fatalf("got %v; reflection got %v", bGen, bPlain)
+	}
+	var val2 mixed
+	gen.Unpack(bGen, &val2)
+	if val2 != val {
+		t.Fatalf("expected %v; got %v", val, val2)
+	}
+}
+
+// Embeds Header, and so has its generated
+// methods, which must not be used to pack it
+type withHeader struct {
+	Header
+	Length uint16
+}
+
+type plainWithHeader struct {
+	Version, Flags uint8 `gopack:"4"`
+	Length         uint16
+}
+
+func TestEmbedded(t *testing.T) {
+	val := withHeader{Header{1, 2}, 0x1234}
+	for _, order := range []gopack.BitOrder{gopack.LSBFirst, gopack.MSBFirst} {
+		cfg := gopack.Config{Order: order}
+		testPack(t, cfg, "withHeader", val, plainWithHeader{1, 2, 0x1234})
+
+		b := cfg.AppendPack(nil, val)
+		var val2 withHeader
+		if err := cfg.UnpackE(b, &val2); err != nil || val2 != val {
+			t.Fatalf("%v: expected %v; got %v (error %v)", cfg, val, val2, err)
+		}
+		vals := make([]withHeader, 2)
+		cfg.UnpackSlice(cfg.AppendPack(b, &val), vals)
+		if vals[0] != val || vals[1] != val {
+			t.Fatalf("%v: expected %v; got %v", cfg, val, vals)
+		}
+	}
+}
+
+var benchMixed = mixed{Flag: true, Month: time.March, Inner: inner{true, 17, 5}, Matrix: [2][2]int8{{1, 2}, {-3, -4}}}
+
+func BenchmarkPackGenerated(b *testing.B) {
+	buf := make([]byte, gopack.PackedSizeof(benchMixed))
+	for i := 0; i < b.N; i++ {
+		gopack.Pack(buf, benchMixed)
+	}
+}
+
+func BenchmarkPackReflection(b *testing.B) {
+	buf := make([]byte, gopack.PackedSizeof(benchMixed))
+	val := plainMixed(benchMixed)
+	for i := 0; i < b.N; i++ {
+		gopack.Pack(buf, val)
+	}
+}
+
+func BenchmarkUnpackGenerated(b *testing.B) {
+	buf := make([]byte, gopack.PackedSizeof(benchMixed))
+	gopack.Pack(buf, benchMixed)
+	var val mixed
+	for i := 0; i < b.N; i++ {
+		gopack.Unpack(buf, &val)
+	}
+}
+
+func BenchmarkUnpackReflection(b *testing.B) {
+	buf := make([]byte, gopack.PackedSizeof(benchMixed))
+	gopack.Pack(buf, benchMixed)
+	var val plainMixed
+	for i := 0; i < b.N; i++ {
+		gopack.Unpack(buf, &val)
+	}
+}
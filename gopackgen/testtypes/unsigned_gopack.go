@@ -0,0 +1,734 @@
+// Code generated by "gopackgen -type=unsigned,signed,mixed,floats,padded,placed,Header"; DO NOT EDIT.
+
+package testtypes
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/synful/gopack"
+)
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of unsigned.
+func (unsigned) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (unsigned) PackedType() reflect.Type { return reflect.TypeOf((*unsigned)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t unsigned) PackBits(b []byte) error {
+	if len(b) < 25 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 25)}
+	}
+	b = b[:25]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if t.U1 > 1 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U1", Offset: 0, Width: 1,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(1), uint64(t.U1))}
+	}
+	u = uint64(t.U1)
+	b[0] |= byte(u)
+	if t.U3 > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U3", Offset: 1, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.U3))}
+	}
+	u = uint64(t.U3)
+	b[0] |= byte(u << 1)
+	u = uint64(t.U8)
+	b[0] |= byte(u << 4)
+	b[1] |= byte(u >> 4)
+	if t.U12 > 4095 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U12", Offset: 12, Width: 12,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(4095), uint64(t.U12))}
+	}
+	u = uint64(t.U12)
+	b[1] |= byte(u << 4)
+	b[2] |= byte(u >> 4)
+	u = uint64(t.U16)
+	b[3] |= byte(u)
+	b[4] |= byte(u >> 8)
+	if t.U29 > 536870911 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U29", Offset: 40, Width: 29,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(536870911), uint64(t.U29))}
+	}
+	u = uint64(t.U29)
+	b[5] |= byte(u)
+	b[6] |= byte(u >> 8)
+	b[7] |= byte(u >> 16)
+	b[8] |= byte(u >> 24)
+	if t.U57 > 144115188075855871 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U57", Offset: 69, Width: 57,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(144115188075855871), uint64(t.U57))}
+	}
+	u = uint64(t.U57)
+	b[8] |= byte(u << 5)
+	b[9] |= byte(u >> 3)
+	b[10] |= byte(u >> 11)
+	b[11] |= byte(u >> 19)
+	b[12] |= byte(u >> 27)
+	b[13] |= byte(u >> 35)
+	b[14] |= byte(u >> 43)
+	b[15] |= byte(u >> 51)
+	u = uint64(t.U64)
+	b[15] |= byte(u << 6)
+	b[16] |= byte(u >> 2)
+	b[17] |= byte(u >> 10)
+	b[18] |= byte(u >> 18)
+	b[19] |= byte(u >> 26)
+	b[20] |= byte(u >> 34)
+	b[21] |= byte(u >> 42)
+	b[22] |= byte(u >> 50)
+	b[23] |= byte(u >> 58)
+	if t.U > 127 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "U", Offset: 190, Width: 7,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(127), uint64(t.U))}
+	}
+	u = uint64(t.U)
+	b[23] |= byte(u << 6)
+	b[24] |= byte(u >> 2)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *unsigned) UnpackBits(b []byte) error {
+	if len(b) < 25 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 25)}
+	}
+	b = b[:25]
+	var u uint64
+	u = uint64(b[0]) & 0x1
+	t.U1 = uint8(u)
+	u = uint64(b[0]) >> 1 & 0x7
+	t.U3 = uint8(u)
+	u = (uint64(b[0])>>4 | uint64(b[1])<<4) & 0xff
+	t.U8 = uint8(u)
+	u = uint64(b[1])>>4 | uint64(b[2])<<4
+	t.U12 = uint16(u)
+	u = uint64(b[3]) | uint64(b[4])<<8
+	t.U16 = uint16(u)
+	u = (uint64(b[5]) | uint64(b[6])<<8 | uint64(b[7])<<16 | uint64(b[8])<<24) & 0x1fffffff
+	t.U29 = uint32(u)
+	u = (uint64(b[8])>>5 | uint64(b[9])<<3 | uint64(b[10])<<11 | uint64(b[11])<<19 | uint64(b[12])<<27 | uint64(b[13])<<35 | uint64(b[14])<<43 | uint64(b[15])<<51) & 0x1ffffffffffffff
+	t.U57 = u
+	u = uint64(b[15])>>6 | uint64(b[16])<<2 | uint64(b[17])<<10 | uint64(b[18])<<18 | uint64(b[19])<<26 | uint64(b[20])<<34 | uint64(b[21])<<42 | uint64(b[22])<<50 | uint64(b[23])<<58
+	t.U64 = u
+	u = (uint64(b[23])>>6 | uint64(b[24])<<2) & 0x7f
+	t.U = uint(u)
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of signed.
+func (signed) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (signed) PackedType() reflect.Type { return reflect.TypeOf((*signed)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t signed) PackBits(b []byte) error {
+	if len(b) < 23 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 23)}
+	}
+	b = b[:23]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if v := int64(t.I1); v < -1 || v > 0 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "I1", Offset: 0, Width: 1,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(0), int64(-1), v)}
+	}
+	u = uint64(t.I1) & 0x1
+	b[0] |= byte(u)
+	if v := int64(t.I5); v < -16 || v > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "I5", Offset: 1, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(15), int64(-16), v)}
+	}
+	u = uint64(t.I5) & 0x1f
+	b[0] |= byte(u << 1)
+	u = uint64(t.I8) & 0xff
+	b[0] |= byte(u << 6)
+	b[1] |= byte(u >> 2)
+	if v := int64(t.I13); v < -4096 || v > 4095 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "I13", Offset: 14, Width: 13,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(4095), int64(-4096), v)}
+	}
+	u = uint64(t.I13) & 0x1fff
+	b[1] |= byte(u << 6)
+	b[2] |= byte(u >> 2)
+	b[3] |= byte(u >> 10)
+	u = uint64(t.I32) & 0xffffffff
+	b[3] |= byte(u << 3)
+	b[4] |= byte(u >> 5)
+	b[5] |= byte(u >> 13)
+	b[6] |= byte(u >> 21)
+	b[7] |= byte(u >> 29)
+	if v := int64(t.I50); v < -562949953421312 || v > 562949953421311 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "I50", Offset: 59, Width: 50,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(562949953421311), int64(-562949953421312), v)}
+	}
+	u = uint64(t.I50) & 0x3ffffffffffff
+	b[7] |= byte(u << 3)
+	b[8] |= byte(u >> 5)
+	b[9] |= byte(u >> 13)
+	b[10] |= byte(u >> 21)
+	b[11] |= byte(u >> 29)
+	b[12] |= byte(u >> 37)
+	b[13] |= byte(u >> 45)
+	u = uint64(t.I64)
+	b[13] |= byte(u << 5)
+	b[14] |= byte(u >> 3)
+	b[15] |= byte(u >> 11)
+	b[16] |= byte(u >> 19)
+	b[17] |= byte(u >> 27)
+	b[18] |= byte(u >> 35)
+	b[19] |= byte(u >> 43)
+	b[20] |= byte(u >> 51)
+	b[21] |= byte(u >> 59)
+	if v := int64(t.I); v < -256 || v > 255 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "I", Offset: 173, Width: 9,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(255), int64(-256), v)}
+	}
+	u = uint64(t.I) & 0x1ff
+	b[21] |= byte(u << 5)
+	b[22] |= byte(u >> 3)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *signed) UnpackBits(b []byte) error {
+	if len(b) < 23 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 23)}
+	}
+	b = b[:23]
+	var u uint64
+	u = uint64(b[0]) & 0x1
+	t.I1 = int8(int64(u<<63) >> 63)
+	u = uint64(b[0]) >> 1 & 0x1f
+	t.I5 = int8(int64(u<<59) >> 59)
+	u = (uint64(b[0])>>6 | uint64(b[1])<<2) & 0xff
+	t.I8 = int8(int64(u<<56) >> 56)
+	u = (uint64(b[1])>>6 | uint64(b[2])<<2 | uint64(b[3])<<10) & 0x1fff
+	t.I13 = int16(int64(u<<51) >> 51)
+	u = (uint64(b[3])>>3 | uint64(b[4])<<5 | uint64(b[5])<<13 | uint64(b[6])<<21 | uint64(b[7])<<29) & 0xffffffff
+	t.I32 = int32(int64(u<<32) >> 32)
+	u = (uint64(b[7])>>3 | uint64(b[8])<<5 | uint64(b[9])<<13 | uint64(b[10])<<21 | uint64(b[11])<<29 | uint64(b[12])<<37 | uint64(b[13])<<45) & 0x3ffffffffffff
+	t.I50 = int64(u<<14) >> 14
+	u = uint64(b[13])>>5 | uint64(b[14])<<3 | uint64(b[15])<<11 | uint64(b[16])<<19 | uint64(b[17])<<27 | uint64(b[18])<<35 | uint64(b[19])<<43 | uint64(b[20])<<51 | uint64(b[21])<<59
+	t.I64 = int64(u)
+	u = (uint64(b[21])>>5 | uint64(b[22])<<3) & 0x1ff
+	t.I = int(int64(u<<55) >> 55)
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of mixed.
+func (mixed) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (mixed) PackedType() reflect.Type { return reflect.TypeOf((*mixed)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t mixed) PackBits(b []byte) error {
+	if len(b) < 8 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 8)}
+	}
+	b = b[:8]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if t.Flag {
+		b[0] |= 0x1
+	}
+	if v := int64(t.Month); v < -8 || v > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Month", Offset: 1, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(7), int64(-8), v)}
+	}
+	u = uint64(t.Month) & 0xf
+	b[0] |= byte(u << 1)
+	if t.Inner.A {
+		b[0] |= 0x20
+	}
+	if t.Inner.B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner.B", Offset: 6, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Inner.B))}
+	}
+	u = uint64(t.Inner.B)
+	b[0] |= byte(u << 6)
+	b[1] |= byte(u >> 2)
+	if t.Inner.Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner.Level", Offset: 11, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Inner.Level))}
+	}
+	u = uint64(t.Inner.Level)
+	b[1] |= byte(u << 3)
+	if t.Array[0].A {
+		b[1] |= 0x40
+	}
+	if t.Array[0].B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[0].B", Offset: 15, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Array[0].B))}
+	}
+	u = uint64(t.Array[0].B)
+	b[1] |= byte(u << 7)
+	b[2] |= byte(u >> 1)
+	if t.Array[0].Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[0].Level", Offset: 20, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Array[0].Level))}
+	}
+	u = uint64(t.Array[0].Level)
+	b[2] |= byte(u << 4)
+	if t.Array[1].A {
+		b[2] |= 0x80
+	}
+	if t.Array[1].B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[1].B", Offset: 24, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Array[1].B))}
+	}
+	u = uint64(t.Array[1].B)
+	b[3] |= byte(u)
+	if t.Array[1].Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[1].Level", Offset: 29, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Array[1].Level))}
+	}
+	u = uint64(t.Array[1].Level)
+	b[3] |= byte(u << 5)
+	if t.Array[2].A {
+		b[4] |= 0x1
+	}
+	if t.Array[2].B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[2].B", Offset: 33, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Array[2].B))}
+	}
+	u = uint64(t.Array[2].B)
+	b[4] |= byte(u << 1)
+	if t.Array[2].Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Array[2].Level", Offset: 38, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Array[2].Level))}
+	}
+	u = uint64(t.Array[2].Level)
+	b[4] |= byte(u << 6)
+	b[5] |= byte(u >> 2)
+	if v := int64(t.Matrix[0][0]); v < -4 || v > 3 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Matrix[0][0]", Offset: 41, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(3), int64(-4), v)}
+	}
+	u = uint64(t.Matrix[0][0]) & 0x7
+	b[5] |= byte(u << 1)
+	if v := int64(t.Matrix[0][1]); v < -4 || v > 3 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Matrix[0][1]", Offset: 44, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(3), int64(-4), v)}
+	}
+	u = uint64(t.Matrix[0][1]) & 0x7
+	b[5] |= byte(u << 4)
+	if v := int64(t.Matrix[1][0]); v < -4 || v > 3 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Matrix[1][0]", Offset: 47, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(3), int64(-4), v)}
+	}
+	u = uint64(t.Matrix[1][0]) & 0x7
+	b[5] |= byte(u << 7)
+	b[6] |= byte(u >> 1)
+	if v := int64(t.Matrix[1][1]); v < -4 || v > 3 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Matrix[1][1]", Offset: 50, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(3), int64(-4), v)}
+	}
+	u = uint64(t.Matrix[1][1]) & 0x7
+	b[6] |= byte(u << 2)
+	u = uint64(t.Typ.F1)
+	b[6] |= byte(u << 5)
+	b[7] |= byte(u >> 3)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *mixed) UnpackBits(b []byte) error {
+	if len(b) < 8 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 8)}
+	}
+	b = b[:8]
+	var u uint64
+	t.Flag = b[0]&0x1 != 0
+	u = uint64(b[0]) >> 1 & 0xf
+	t.Month = time.Month(int64(u<<60) >> 60)
+	t.Inner.A = b[0]&0x20 != 0
+	u = (uint64(b[0])>>6 | uint64(b[1])<<2) & 0x1f
+	t.Inner.B = uint8(u)
+	u = uint64(b[1]) >> 3 & 0x7
+	t.Inner.Level = level(u)
+	t.Array[0].A = b[1]&0x40 != 0
+	u = (uint64(b[1])>>7 | uint64(b[2])<<1) & 0x1f
+	t.Array[0].B = uint8(u)
+	u = uint64(b[2]) >> 4 & 0x7
+	t.Array[0].Level = level(u)
+	t.Array[1].A = b[2]&0x80 != 0
+	u = uint64(b[3]) & 0x1f
+	t.Array[1].B = uint8(u)
+	u = uint64(b[3]) >> 5
+	t.Array[1].Level = level(u)
+	t.Array[2].A = b[4]&0x1 != 0
+	u = uint64(b[4]) >> 1 & 0x1f
+	t.Array[2].B = uint8(u)
+	u = (uint64(b[4])>>6 | uint64(b[5])<<2) & 0x7
+	t.Array[2].Level = level(u)
+	u = uint64(b[5]) >> 1 & 0x7
+	t.Matrix[0][0] = int8(int64(u<<61) >> 61)
+	u = uint64(b[5]) >> 4 & 0x7
+	t.Matrix[0][1] = int8(int64(u<<61) >> 61)
+	u = (uint64(b[5])>>7 | uint64(b[6])<<1) & 0x7
+	t.Matrix[1][0] = int8(int64(u<<61) >> 61)
+	u = uint64(b[6]) >> 2 & 0x7
+	t.Matrix[1][1] = int8(int64(u<<61) >> 61)
+	u = (uint64(b[6])>>5 | uint64(b[7])<<3) & 0xff
+	t.Typ.F1 = uint8(u)
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of floats.
+func (floats) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (floats) PackedType() reflect.Type { return reflect.TypeOf((*floats)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t floats) PackBits(b []byte) error {
+	if len(b) < 25 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 25)}
+	}
+	b = b[:25]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	var f, n float64
+	u = uint64(math.Float32bits(float32(float64(t.Raw32))))
+	b[0] |= byte(u)
+	b[1] |= byte(u >> 8)
+	b[2] |= byte(u >> 16)
+	b[3] |= byte(u >> 24)
+	u = math.Float64bits(float64(t.Raw64))
+	b[4] |= byte(u)
+	b[5] |= byte(u >> 8)
+	b[6] |= byte(u >> 16)
+	b[7] |= byte(u >> 24)
+	b[8] |= byte(u >> 32)
+	b[9] |= byte(u >> 40)
+	b[10] |= byte(u >> 48)
+	b[11] |= byte(u >> 56)
+	f = float64(t.Volts)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Volts", Offset: 96, Width: 12,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f-0)/0.0625 + 0.5)
+	if n < 0 || n >= 4096 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Volts", Offset: 96, Width: 12,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(255.9375), float64(0), f)}
+	}
+	u = uint64(n)
+	b[12] |= byte(u)
+	b[13] |= byte(u >> 8)
+	f = float64(t.Temp)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Temp", Offset: 108, Width: 10,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f - -40)/0.05 + 0.5)
+	if n < 0 || n >= 1024 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Temp", Offset: 108, Width: 10,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(11.150000000000006), float64(-40), f)}
+	}
+	u = uint64(n)
+	b[13] |= byte(u << 4)
+	b[14] |= byte(u >> 4)
+	f = float64(t.Delta)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Delta", Offset: 118, Width: 8,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f-0)/0.25 + 0.5)
+	if n < -128 || n >= 128 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Delta", Offset: 118, Width: 8,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(31.75), float64(-32), f)}
+	}
+	u = uint64(int64(n)) & 0xff
+	b[14] |= byte(u << 6)
+	b[15] |= byte(u >> 2)
+	f = float64(t.Coarse)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Coarse", Offset: 126, Width: 3,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f-0)/100 + 0.5)
+	if n < -4 || n >= 4 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Coarse", Offset: 126, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(300), float64(-400), f)}
+	}
+	u = uint64(int64(n)) & 0x7
+	b[15] |= byte(u << 6)
+	b[16] |= byte(u >> 2)
+	f = float64(t.Wide)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return gopack.Error{Kind: gopack.Unrepresentable, Path: "Wide", Offset: 129, Width: 64,
+			Err: fmt.Errorf("value not representable: %v", f)}
+	}
+	n = math.Floor((f-0)/0.00390625 + 0.5)
+	if n < 0 || n >= 1.8446744073709552e+19 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Wide", Offset: 129, Width: 64,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", float64(7.205759403792794e+16), float64(0), f)}
+	}
+	u = uint64(n)
+	b[16] |= byte(u << 1)
+	b[17] |= byte(u >> 7)
+	b[18] |= byte(u >> 15)
+	b[19] |= byte(u >> 23)
+	b[20] |= byte(u >> 31)
+	b[21] |= byte(u >> 39)
+	b[22] |= byte(u >> 47)
+	b[23] |= byte(u >> 55)
+	b[24] |= byte(u >> 63)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *floats) UnpackBits(b []byte) error {
+	if len(b) < 25 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 25)}
+	}
+	b = b[:25]
+	var u uint64
+	u = uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24
+	t.Raw32 = float32(float64(math.Float32frombits(uint32(u))))
+	u = uint64(b[4]) | uint64(b[5])<<8 | uint64(b[6])<<16 | uint64(b[7])<<24 | uint64(b[8])<<32 | uint64(b[9])<<40 | uint64(b[10])<<48 | uint64(b[11])<<56
+	t.Raw64 = math.Float64frombits(u)
+	u = (uint64(b[12]) | uint64(b[13])<<8) & 0xfff
+	t.Volts = float32(float64(float64(u)*0.0625) + 0)
+	u = (uint64(b[13])>>4 | uint64(b[14])<<4) & 0x3ff
+	t.Temp = float64(float64(u)*0.05) + -40
+	u = (uint64(b[14])>>6 | uint64(b[15])<<2) & 0xff
+	t.Delta = float64(float64(int64(u<<56)>>56)*0.25) + 0
+	u = (uint64(b[15])>>6 | uint64(b[16])<<2) & 0x7
+	t.Coarse = float32(float64(float64(int64(u<<61)>>61)*100) + 0)
+	u = uint64(b[16])>>1 | uint64(b[17])<<7 | uint64(b[18])<<15 | uint64(b[19])<<23 | uint64(b[20])<<31 | uint64(b[21])<<39 | uint64(b[22])<<47 | uint64(b[23])<<55 | uint64(b[24])<<63
+	t.Wide = float64(float64(u)*0.00390625) + 0
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of padded.
+func (padded) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (padded) PackedType() reflect.Type { return reflect.TypeOf((*padded)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t padded) PackBits(b []byte) error {
+	if len(b) < 12 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 12)}
+	}
+	b = b[:12]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if t.A > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "A", Offset: 0, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.A))}
+	}
+	u = uint64(t.A)
+	b[0] |= byte(u)
+	b[0] |= 0x18
+	if t.B > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "B", Offset: 5, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(15), uint64(t.B))}
+	}
+	u = uint64(t.B)
+	b[0] |= byte(u << 5)
+	b[1] |= byte(u >> 3)
+	if t.C {
+		b[9] |= 0x80
+	}
+	b[10] |= 0x5c
+	b[11] |= 0x1a
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *padded) UnpackBits(b []byte) error {
+	if len(b) < 12 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 12)}
+	}
+	b = b[:12]
+	var u uint64
+	u = uint64(b[0]) & 0x7
+	t.A = uint8(u)
+	u = (uint64(b[0])>>5 | uint64(b[1])<<3) & 0xf
+	t.B = uint8(u)
+	if v := uint64(b[1])>>1 | uint64(b[2])<<7 | uint64(b[3])<<15 | uint64(b[4])<<23 | uint64(b[5])<<31 | uint64(b[6])<<39 | uint64(b[7])<<47 | uint64(b[8])<<55 | uint64(b[9])<<63; v != 0 {
+		return gopack.Error{Kind: gopack.BadPadding, Path: "_", Offset: 9, Width: 70,
+			Err: fmt.Errorf("padding has unexpected value: expected %v; got %v", uint64(0), v)}
+	}
+	if v := uint64(b[9]) >> 1 & 0x3f; v != 0 {
+		return gopack.Error{Kind: gopack.BadPadding, Path: "_", Offset: 9, Width: 70,
+			Err: fmt.Errorf("padding has unexpected value: expected %v; got %v", uint64(0), v)}
+	}
+	t.C = b[9]&0x80 != 0
+	if v := (uint64(b[10]) | uint64(b[11])<<8) & 0x1fff; v != 6748 {
+		return gopack.Error{Kind: gopack.BadPadding, Path: "_", Offset: 80, Width: 13,
+			Err: fmt.Errorf("padding has unexpected value: expected %v; got %v", uint64(6748), v)}
+	}
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of placed.
+func (placed) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (placed) PackedType() reflect.Type { return reflect.TypeOf((*placed)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t placed) PackBits(b []byte) error {
+	if len(b) < 7 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 7)}
+	}
+	b = b[:7]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if t.Flag {
+		b[3] |= 0x80
+	}
+	if t.Mode > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Mode", Offset: 4, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Mode))}
+	}
+	u = uint64(t.Mode)
+	b[0] |= byte(u << 4)
+	if t.Count > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Count", Offset: 17, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Count))}
+	}
+	u = uint64(t.Count)
+	b[2] |= byte(u << 1)
+	if v := int64(t.Next); v < -2 || v > 1 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Next", Offset: 22, Width: 2,
+			Err: fmt.Errorf("value out of range: max %v, min %v; got %v", int64(1), int64(-2), v)}
+	}
+	u = uint64(t.Next) & 0x3
+	b[2] |= byte(u << 6)
+	if t.Inner.A {
+		b[5] |= 0x1
+	}
+	if t.Inner.B > 31 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner.B", Offset: 41, Width: 5,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(31), uint64(t.Inner.B))}
+	}
+	u = uint64(t.Inner.B)
+	b[5] |= byte(u << 1)
+	if t.Inner.Level > 7 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Inner.Level", Offset: 46, Width: 3,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(7), uint64(t.Inner.Level))}
+	}
+	u = uint64(t.Inner.Level)
+	b[5] |= byte(u << 6)
+	b[6] |= byte(u >> 2)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *placed) UnpackBits(b []byte) error {
+	if len(b) < 7 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 7)}
+	}
+	b = b[:7]
+	var u uint64
+	t.Flag = b[3]&0x80 != 0
+	u = uint64(b[0]) >> 4 & 0x7
+	t.Mode = uint8(u)
+	u = uint64(b[2]) >> 1 & 0x1f
+	t.Count = uint16(u)
+	u = uint64(b[2]) >> 6
+	t.Next = int8(int64(u<<62) >> 62)
+	t.Inner.A = b[5]&0x1 != 0
+	u = uint64(b[5]) >> 1 & 0x1f
+	t.Inner.B = uint8(u)
+	u = (uint64(b[5])>>6 | uint64(b[6])<<2) & 0x7
+	t.Inner.Level = level(u)
+	return nil
+}
+
+// BitOrder returns the order in which PackBits
+// and UnpackBits lay out the fields of Header.
+func (Header) BitOrder() gopack.BitOrder { return gopack.LSBFirst }
+
+// PackedType returns the type for which PackBits
+// and UnpackBits were generated.
+func (Header) PackedType() reflect.Type { return reflect.TypeOf((*Header)(nil)).Elem() }
+
+// PackBits packs t into b without using reflection.
+// It is equivalent to gopack.PackE(b, t).
+func (t Header) PackBits(b []byte) error {
+	if len(b) < 1 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 1)}
+	}
+	b = b[:1]
+	for i := range b {
+		b[i] = 0
+	}
+	var u uint64
+	if t.Version > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Version", Offset: 0, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(15), uint64(t.Version))}
+	}
+	u = uint64(t.Version)
+	b[0] |= byte(u)
+	if t.Flags > 15 {
+		return gopack.Error{Kind: gopack.Overflow, Path: "Flags", Offset: 4, Width: 4,
+			Err: fmt.Errorf("value out of range: max %v; got %v", uint64(15), uint64(t.Flags))}
+	}
+	u = uint64(t.Flags)
+	b[0] |= byte(u << 4)
+	return nil
+}
+
+// UnpackBits unpacks b into t without using reflection.
+// It is equivalent to gopack.UnpackE(b, t).
+func (t *Header) UnpackBits(b []byte) error {
+	if len(b) < 1 {
+		return gopack.Error{Kind: gopack.ShortBuffer, Err: fmt.Errorf("buffer too small (%v; need %v)", len(b), 1)}
+	}
+	b = b[:1]
+	var u uint64
+	u = uint64(b[0]) & 0xf
+	t.Version = uint8(u)
+	u = uint64(b[0]) >> 4
+	t.Flags = uint8(u)
+	return nil
+}
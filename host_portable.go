@@ -0,0 +1,14 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !386 && !amd64 && !amd64p32 && !arm64 && !ppc64le
+// +build !386,!amd64,!amd64p32,!arm64,!ppc64le
+
+package gopack
+
+// On big-endian hosts and hosts with strict
+// alignment requirements, the byte-at-a-time
+// packers in pack-portable.go are used instead
+// of the packers in pack.go.
+const unalignedLittleEndian = false
@@ -0,0 +1,15 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build 386 || amd64 || amd64p32 || arm64 || ppc64le
+// +build 386 amd64 amd64p32 arm64 ppc64le
+
+package gopack
+
+// The packers in pack.go read and write
+// multi-byte words through unsafe pointer
+// casts, which only produces the LSBFirst
+// layout on little-endian hosts which
+// permit unaligned memory access.
+const unalignedLittleEndian = true
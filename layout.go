@@ -0,0 +1,206 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"sort"
+)
+
+// A BitRange is the range of bits
+// [Offset, Offset+Width) within
+// packed data.
+type BitRange struct {
+	Offset, Width uint64
+}
+
+// A Layout describes the packed form of a struct type.
+type Layout struct {
+	// Fields holds the layouts of the struct's
+	// fields, in declaration order. Unexported
+	// fields, which are not packed, are omitted.
+	Fields []FieldLayout
+
+	// Bits is the number of bits the struct
+	// occupies, and Bytes is the number of
+	// bytes required to hold them (that is,
+	// the value returned by PackedSizeof).
+	Bits  uint64
+	Bytes int
+}
+
+// A FieldLayout describes the position of
+// a single field within packed data.
+type FieldLayout struct {
+	// Name is the field's name, or "_" for
+	// padding fields. For array elements,
+	// it is the index in brackets ("[3]").
+	Name string
+
+	// Path is the dotted path of the field
+	// relative to the top-level struct, as
+	// used in Error.
+	Path string
+
+	// GoType is the field's declared type.
+	GoType reflect.Type
+
+	// BitOffset and BitWidth are the position and
+	// size of the field, in bits, relative to the
+	// start of the packed data. Bit 0 is the least
+	// significant bit of the first byte in LSBFirst
+	// order, and the most significant in MSBFirst.
+	BitOffset, BitWidth uint64
+
+	// Signed reports whether the field is stored
+	// as a two's complement integer (signed
+	// integers and floats with the "signed"
+	// option).
+	Signed bool
+
+	// Children holds the layouts of the fields
+	// of a struct or the elements of an array.
+	Children []FieldLayout
+}
+
+// LayoutOf returns the layout of the packed
+// form of strct, which must be a struct or
+// a pointer to a struct. The layout is the
+// same in either bit order.
+//
+// If the type of strct cannot be packed,
+// LayoutOf will panic.
+func LayoutOf(strct interface{}) Layout {
+	p, err := structPlan(reflect.TypeOf(strct))
+	if err != nil {
+		panic(err)
+	}
+	return Layout{p.layout().Children, p.bits, bitsToBytes(p.bits)}
+}
+
+// Gaps returns the ranges of bits in the packed
+// form of strct which are not occupied by any
+// field, in increasing order of offset. Gaps can
+// only occur in structs whose fields are placed
+// at explicit offsets (see Pack). Gaps are packed
+// as zero and ignored when unpacking. Bits in the
+// last byte beyond the end of the packed data are
+// not considered gaps.
+//
+// If the type of strct cannot be packed, Gaps
+// will panic.
+func Gaps(strct interface{}) []BitRange {
+	p, err := structPlan(reflect.TypeOf(strct))
+	if err != nil {
+		panic(err)
+	}
+	gaps := p.allGaps()
+	sort.Sort(byOffset(gaps))
+	return gaps
+}
+
+// Returns the plan for strct, which must
+// be a struct or a pointer to a struct.
+func structPlan(strct reflect.Type) (*plan, error) {
+	p, err := planFor(strct)
+	if err != nil {
+		return nil, err
+	}
+	if p.kind != planStruct {
+		return nil, newError(NonStruct, "non-struct type %v", p.typ.String())
+	}
+	if p.varSize {
+		return nil, newError(UnsupportedType, "variable-size type %v has no fixed layout", p.typ.String())
+	}
+	return p, nil
+}
+
+// A placer assigns bit offsets to the fields
+// of a struct which is placed at base. Fields
+// are placed sequentially in declaration order
+// unless their tags specify an offset (relative
+// to the start of the struct) with "offset=<bits>".
+// A field without an offset is placed immediately
+// after the previous field. The struct occupies
+// the bits up to the end of its furthest field.
+type placer struct {
+	base uint64
+	// Relative offsets of the next sequential
+	// field and of the end of the furthest field
+	next, end uint64
+	used      []placedField
+}
+
+type placedField struct {
+	BitRange
+	path string
+}
+
+// Returns the absolute offset at which
+// field (whose path is path) is placed.
+func (p *placer) start(path string, field reflect.StructField) (uint64, error) {
+	lsb := p.base + p.next
+	t, err := parseTag(path, lsb, field.Tag)
+	if err != nil {
+		return 0, err
+	}
+	if !t.has("offset") || t.floatOffset(field.Type) {
+		return lsb, nil
+	}
+	off, err := t.getInt(path, lsb, "offset", 0)
+	if err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: negative offset (%d)", off)
+	}
+	return p.base + uint64(off), nil
+}
+
+// Records that the field at path occupies
+// bits [lsb, lsb+bits), reporting an error
+// if it overlaps a previously placed field.
+func (p *placer) add(path string, lsb, bits uint64) error {
+	r := BitRange{lsb - p.base, bits}
+	if bits > 0 {
+		for _, u := range p.used {
+			if r.Offset < u.Offset+u.Width && u.Offset < r.Offset+r.Width {
+				return newFieldError(Overlap, path, lsb, bits, "field overlaps field %s", u.path)
+			}
+		}
+		p.used = append(p.used, placedField{r, path})
+	}
+	p.next = r.Offset + bits
+	if p.next > p.end {
+		p.end = p.next
+	}
+	return nil
+}
+
+// Returns the absolute ranges within the
+// struct which are not occupied by any field.
+func (p *placer) gaps() []BitRange {
+	used := make([]BitRange, len(p.used))
+	for i, u := range p.used {
+		used[i] = u.BitRange
+	}
+	sort.Sort(byOffset(used))
+
+	var gaps []BitRange
+	var off uint64
+	for _, u := range used {
+		if u.Offset > off {
+			gaps = append(gaps, BitRange{p.base + off, u.Offset - off})
+		}
+		off = u.Offset + u.Width
+	}
+	return gaps
+}
+
+type byOffset []BitRange
+
+func (b byOffset) Len() int           { return len(b) }
+func (b byOffset) Less(i, j int) bool { return b[i].Offset < b[j].Offset }
+func (b byOffset) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
@@ -0,0 +1,222 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestOffsets(t *testing.T) {
+	type register struct {
+		Flag   bool   `gopack:"offset=31"`
+		Enable bool   `gopack:"offset=0"`
+		Mode   uint8  `gopack:"offset=4,width=3"`
+		Count  uint16 `gopack:"offset=17,width=5"`
+		Next   uint8  `gopack:"2"` // Follows Count
+	}
+
+	if sz := PackedSizeof(register{}); sz != 4 {
+		t.Errorf("Expected a packed size of 4 but got %d", sz)
+	}
+
+	b := []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	val := register{true, true, 5, 21, 3}
+	Pack(b, val)
+	// Count = 10101 at 17, Next = 11 at 22
+	expect := []byte{0x51, 0x00, 0xEA, 0x80}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %#v; got %#v", expect, b)
+	}
+
+	var val2 register
+	Unpack(b, &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	gaps := Gaps(register{})
+	expectGaps := []BitRange{{1, 3}, {7, 10}, {24, 7}}
+	if !reflect.DeepEqual(gaps, expectGaps) {
+		t.Fatalf("Expected gaps %v; got %v", expectGaps, gaps)
+	}
+}
+
+func TestNestedOffsets(t *testing.T) {
+	type inner struct {
+		A uint8 `gopack:"offset=4,width=4"`
+	}
+	type typ struct {
+		F1 uint8 `gopack:"4"`
+		F2 inner `gopack:"offset=8"`
+		F3 inner
+	}
+
+	if sz := PackedSizeof(typ{}); sz != 3 {
+		t.Errorf("Expected a packed size of 3 but got %d", sz)
+	}
+
+	var b [3]byte
+	val := typ{0xA, inner{0xB}, inner{0xC}}
+	Pack(b[:], val)
+	expect := [...]byte{0x0A, 0xB0, 0xC0}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	gaps := Gaps(&typ{})
+	expectGaps := []BitRange{{4, 4}, {8, 4}, {16, 4}}
+	if !reflect.DeepEqual(gaps, expectGaps) {
+		t.Fatalf("Expected gaps %v; got %v", expectGaps, gaps)
+	}
+
+	type dense struct {
+		F1 uint8 `gopack:"3"`
+		F2 [2]inner
+	}
+	if gaps := Gaps(dense{}); len(gaps) != 2 {
+		t.Fatalf("Expected 2 gaps; got %v", gaps)
+	}
+}
+
+func TestOffsetMSBFirst(t *testing.T) {
+	type typ struct {
+		F1 uint8 `gopack:"offset=12,width=4"`
+		F2 uint8 `gopack:"offset=0,width=4"`
+	}
+
+	var b [2]byte
+	val := typ{0x3, 0xC}
+	Config{Order: MSBFirst}.Pack(b[:], val)
+	expect := [...]byte{0xC0, 0x03}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Config{Order: MSBFirst}.Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+}
+
+func TestFloatOffsets(t *testing.T) {
+	// On a float field, "offset" is the value
+	// offset unless the width is given as "width"
+	type typ struct {
+		A float64 `gopack:"offset=8,width=8,scale=0.5,bias=-10"`
+		B float64 `gopack:"8,scale=0.5,offset=-10"` // Follows A
+	}
+
+	var b [3]byte
+	val := typ{0, -5}
+	Pack(b[:], val)
+	// A = 20 at 8, B = 10 at 16
+	expect := [...]byte{0x00, 0x14, 0x0A}
+	if b != expect {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b[:], &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	type typ1 struct {
+		F1 float64 `gopack:"8,scale=0.5,offset=-10,bias=-10"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: options \"bias\" and \"offset\" are mutually exclusive", func() {
+		Pack(nil, typ1{})
+	})
+}
+
+func TestOffsetErrors(t *testing.T) {
+	type typ struct {
+		F1 uint8 `gopack:"offset=0,width=5"`
+		F2 uint8 `gopack:"offset=4,width=4"`
+	}
+	testError(t, Overlap, "gopack: F2: field overlaps field F1", func() {
+		Pack(nil, typ{})
+	})
+	testError(t, Overlap, "gopack: F2: field overlaps field F1", func() {
+		Unpack(nil, &typ{})
+	})
+
+	type typ1 struct {
+		F1 uint8
+		F2 bool
+		F3 uint8 `gopack:"offset=7"`
+	}
+	testError(t, Overlap, "gopack: F3: field overlaps field F1", func() {
+		Gaps(typ1{})
+	})
+
+	type typ2 struct {
+		F1 uint8 `gopack:"offset=-1"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: negative offset (-1)", func() {
+		Pack(nil, typ2{})
+	})
+
+	type typ3 struct {
+		F1 uint8 `gopack:"4,width=4"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: duplicate option \"width\"", func() {
+		Pack(nil, typ3{})
+	})
+}
+
+func TestLayoutOf(t *testing.T) {
+	type inner struct {
+		A uint8 `gopack:"offset=2,width=3"`
+	}
+	type typ struct {
+		F1  int8     `gopack:"4"`
+		_   struct{} `gopack:"pad=2"`
+		F2  [2]inner
+		F3  float32 `gopack:"6,fixed=1,signed"`
+		F4  bool
+		int uint8
+	}
+
+	u8 := reflect.TypeOf(uint8(0))
+	it := reflect.TypeOf(inner{})
+	elem := func(i int, off uint64) FieldLayout {
+		idx := "[" + strconv.Itoa(i) + "]"
+		return FieldLayout{idx, "F2" + idx, it, off, 5, false,
+			[]FieldLayout{{"A", "F2" + idx + ".A", u8, off + 2, 3, false, nil}}}
+	}
+	expect := Layout{
+		Fields: []FieldLayout{
+			{"F1", "F1", reflect.TypeOf(int8(0)), 0, 4, true, nil},
+			{"_", "_", reflect.TypeOf(struct{}{}), 4, 2, false, nil},
+			{"F2", "F2", reflect.TypeOf([2]inner{}), 6, 10, false, []FieldLayout{elem(0, 6), elem(1, 11)}},
+			{"F3", "F3", reflect.TypeOf(float32(0)), 16, 6, true, nil},
+			{"F4", "F4", reflect.TypeOf(false), 22, 1, false, nil},
+		},
+		Bits:  23,
+		Bytes: 3,
+	}
+
+	got := LayoutOf(&typ{})
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("Expected layout\n%+v\ngot\n%+v", expect, got)
+	}
+	if got.Bytes != PackedSizeof(typ{}) {
+		t.Errorf("Layout size %d does not match PackedSizeof (%d)", got.Bytes, PackedSizeof(typ{}))
+	}
+
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
+		LayoutOf(0)
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"math"
+	"reflect"
+)
+
+// The packers in this file are built on
+// the byte-at-a-time functions in bits.go,
+// and so work for either bit order and on
+// any host.
+
+func makeUnsignedBitsPacker(path string, typ reflect.Type, lsb uint64, width uint8, put putBitsFunc) packer {
+	canOverflow := width != uint8(typ.Bits())
+	maxVal := uint64(math.MaxUint64) >> (64 - width)
+	if canOverflow {
+		return func(b []byte, field reflect.Value) error {
+			u := field.Uint()
+			if u > maxVal {
+				return newFieldError(Overflow, path, lsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
+			}
+			put(b, lsb, width, u)
+			return nil
+		}
+	}
+	return func(b []byte, field reflect.Value) error {
+		put(b, lsb, width, field.Uint())
+		return nil
+	}
+}
+
+func makeSignedBitsPacker(path string, typ reflect.Type, lsb uint64, width uint8, put putBitsFunc) packer {
+	canOverflow := width != uint8(typ.Bits())
+	minVal := int64(-1) << (width - 1)
+	maxVal := int64(uint64(math.MaxUint64) >> (65 - width))
+	shift := 64 - width
+	if canOverflow {
+		return func(b []byte, field reflect.Value) error {
+			val := field.Int()
+			if val < minVal || val > maxVal {
+				return newFieldError(Overflow, path, lsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
+			}
+			put(b, lsb, width, (uint64(val)<<shift)>>shift)
+			return nil
+		}
+	}
+	return func(b []byte, field reflect.Value) error {
+		put(b, lsb, width, (uint64(field.Int())<<shift)>>shift)
+		return nil
+	}
+}
+
+func makeBoolBitsPacker(lsb uint64, put putBitsFunc) packer {
+	return func(b []byte, field reflect.Value) error {
+		if field.Bool() {
+			put(b, lsb, 1, 1)
+		}
+		return nil
+	}
+}
+
+func makeUnsignedBitsUnpacker(lsb uint64, width uint8, get getBitsFunc) unpacker {
+	return func(b []byte, field reflect.Value) error {
+		field.SetUint(get(b, lsb, width))
+		return nil
+	}
+}
+
+func makeSignedBitsUnpacker(lsb uint64, width uint8, get getBitsFunc) unpacker {
+	shift := 64 - width
+	return func(b []byte, field reflect.Value) error {
+		field.SetInt(int64(get(b, lsb, width)<<shift) >> shift)
+		return nil
+	}
+}
+
+func makeBoolBitsUnpacker(lsb uint64, get getBitsFunc) unpacker {
+	return func(b []byte, field reflect.Value) error {
+		field.SetBool(get(b, lsb, 1) != 0)
+		return nil
+	}
+}
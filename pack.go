@@ -5,174 +5,217 @@
 package gopack
 
 import (
-	"fmt"
 	"math"
 	"reflect"
 	"unsafe"
 )
 
-func makeUnsignedSinglePacker(typ reflect.Type, ilsb uint64, width uint8) packer {
+// The unsafe accesses in the packers and unpackers
+// in this file are always confined to the bytes
+// spanned by the field, [firstByte, lastByte].
+// Each closure which performs unsafe accesses
+// first indexes b[lastByte] so that it cannot read
+// or write past the end of b, even if it is handed
+// a buffer shorter than the one it was built for.
+
+func makeUnsignedSinglePacker(path string, typ reflect.Type, ilsb uint64, width uint8) packer {
 	firstByte := ilsb / 8
 	lsb := uint8(ilsb % 8)
+	lastByte := (ilsb + uint64(width) - 1) / 8
 	canOverflow := width != uint8(typ.Bits())
 	maxVal := (uint64(1) << width) - 1
 	switch {
 	case lsb+width <= 8:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				b[firstByte] |= byte(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
 				b[firstByte] |= byte(field.Uint() << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 16:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(field.Uint() << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 24:
 		shift := 16 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
 				b[firstByte+2] |= byte(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
 				b[firstByte+2] |= byte(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 32:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(field.Uint() << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 40:
 		shift := 32 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				b[firstByte+4] |= byte(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				b[firstByte+4] |= byte(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 48:
 		shift := 32 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 56:
 		shift1 := 32 - lsb
 		shift2 := 48 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift1)
 				b[firstByte+6] |= byte(u >> shift2)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift1)
 				b[firstByte+6] |= byte(u >> shift2)
+				return nil
 			}
 		}
 	case lsb+width <= 64:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= field.Uint() << lsb
+				return nil
 			}
 		}
 	default:
 		// Assume lsb+width <= 72
 		shift := 64 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				if u > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v; got %v", maxVal, u)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v; got %v", maxVal, u)
 				}
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
-				b[firstByte+8] = byte(u >> shift)
+				b[firstByte+8] |= byte(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				u := field.Uint()
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
-				b[firstByte+8] = byte(u >> shift)
+				b[firstByte+8] |= byte(u >> shift)
+				return nil
 			}
 		}
 	}
 }
 
-func makeSignedSinglePacker(typ reflect.Type, ilsb uint64, width uint8) packer {
+func makeSignedSinglePacker(path string, typ reflect.Type, ilsb uint64, width uint8) packer {
 	firstByte := ilsb / 8
 	lsb := uint8(ilsb % 8)
+	lastByte := (ilsb + uint64(width) - 1) / 8
 	canOverflow := width != uint8(typ.Bits())
 	minVal := int64(-1) << (width - 1)
 	maxUval := uint64(math.MaxUint64) >> (65 - width)
@@ -180,153 +223,183 @@ func makeSignedSinglePacker(typ reflect.Type, ilsb uint64, width uint8) packer {
 	switch {
 	case lsb+width <= 8:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				b[firstByte] |= byte(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				b[firstByte] |= byte(u << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 16:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 24:
 		shift := 16 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
 				b[firstByte+2] |= byte(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint16)(unsafe.Pointer(&b[firstByte])) |= uint16(u << lsb)
 				b[firstByte+2] |= byte(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 32:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
+				return nil
 			}
 		}
 	case lsb+width <= 40:
 		shift := 32 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				b[firstByte+4] |= byte(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				b[firstByte+4] |= byte(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 48:
 		shift := 32 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift)
+				return nil
 			}
 		}
 	case lsb+width <= 56:
 		shift1 := 32 - lsb
 		shift2 := 48 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift1)
 				b[firstByte+6] |= byte(u >> shift2)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint32)(unsafe.Pointer(&b[firstByte])) |= uint32(u << lsb)
 				*(*uint16)(unsafe.Pointer(&b[firstByte+4])) |= uint16(u >> shift1)
 				b[firstByte+6] |= byte(u >> shift2)
+				return nil
 			}
 		}
 	case lsb+width <= 64:
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << (64 - width)) >> (64 - width)
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
+				return nil
 			}
 		}
 	default:
@@ -335,21 +408,25 @@ func makeSignedSinglePacker(typ reflect.Type, ilsb uint64, width uint8) packer {
 		shift1 := 64 - width
 		shift2 := 64 - lsb
 		if canOverflow {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				if val < minVal || val > maxVal {
-					panic(Error{fmt.Errorf("gopack: value out of range: max %v, min %v; got %v", maxVal, minVal, val)})
+					return newFieldError(Overflow, path, ilsb, uint64(width), "value out of range: max %v, min %v; got %v", maxVal, minVal, val)
 				}
 				u := (uint64(val) << shift1) >> shift1
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
 				b[firstByte+8] |= byte(u >> shift2)
+				return nil
 			}
 		} else {
-			return func(b []byte, field reflect.Value) {
+			return func(b []byte, field reflect.Value) error {
+				_ = b[lastByte]
 				val := field.Int()
 				u := (uint64(val) << shift1) >> shift1
 				*(*uint64)(unsafe.Pointer(&b[firstByte])) |= u << lsb
 				b[firstByte+8] |= byte(u >> shift2)
+				return nil
 			}
 		}
 	}
@@ -358,69 +435,87 @@ func makeSignedSinglePacker(typ reflect.Type, ilsb uint64, width uint8) packer {
 func makeUnsignedSingleUnpacker(typ reflect.Type, ilsb uint64, width uint8) unpacker {
 	firstByte := ilsb / 8
 	lsb := uint8(ilsb % 8)
+	lastByte := (ilsb + uint64(width) - 1) / 8
 	switch {
 	case lsb+width <= 8:
 		shift1 := 8 - (lsb + width)
 		shift2 := 8 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
 			field.SetUint(uint64((b[firstByte] << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 16:
 		shift1 := 16 - (lsb + width)
 		shift2 := 16 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetUint(uint64((*(*uint16)(unsafe.Pointer(&b[firstByte])) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 24:
 		shift1 := 64 - ((lsb + width) - 16)
 		shift2 := (shift1 + lsb) - 16
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			u := uint64(*(*uint16)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetUint(u | ((uint64(b[firstByte+2]) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 32:
 		shift1 := 32 - (lsb + width)
 		shift2 := 32 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetUint(uint64((*(*uint32)(unsafe.Pointer(&b[firstByte])) << shift1) >> shift2))
+			return nil
 		}
 
 	case lsb+width <= 40:
 		shift1 := 64 - ((lsb + width) - 32)
 		shift2 := (shift1 + lsb) - 32
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			u := uint64(*(*uint32)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetUint(u | ((uint64(b[firstByte+4]) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 48:
 		shift1 := 64 - ((lsb + width) - 32)
 		shift2 := (shift1 + lsb) - 32
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			u := uint64(*(*uint32)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetUint(u | ((uint64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 56:
 		shift1 := 32 - lsb
 		shift2 := 64 - ((lsb + width) - 48)
 		shift3 := (shift2 + lsb) - 48
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			u := uint64(*(*uint32)(unsafe.Pointer(&b[firstByte])) >> lsb)
-			u |= uint64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) >> shift1
+			u |= uint64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) << shift1
 			field.SetUint(u | (uint64(b[firstByte+6])<<shift2)>>shift3)
+			return nil
 		}
 	case lsb+width <= 64:
 		shift1 := 64 - (lsb + width)
 		shift2 := 64 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetUint((*(*uint64)(unsafe.Pointer(&b[firstByte])) << shift1) >> shift2)
+			return nil
 		}
 	default:
 		// Assume lsb+width <= 72
 		shift1 := 128 - (lsb + width)
 		shift2 := (shift1 + lsb) - 64
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			u := *(*uint64)(unsafe.Pointer(&b[firstByte])) >> lsb
 			field.SetUint(u | ((uint64(b[firstByte+8]) << shift1) >> shift2))
+			return nil
 		}
 	}
 }
@@ -428,69 +523,87 @@ func makeUnsignedSingleUnpacker(typ reflect.Type, ilsb uint64, width uint8) unpa
 func makeSignedSingleUnpacker(typ reflect.Type, ilsb uint64, width uint8) unpacker {
 	firstByte := ilsb / 8
 	lsb := uint8(ilsb % 8)
+	lastByte := (ilsb + uint64(width) - 1) / 8
 	switch {
 	case lsb+width <= 8:
 		shift1 := 64 - (lsb + width)
 		shift2 := 64 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
 			field.SetInt((int64(b[firstByte]) << shift1) >> shift2)
+			return nil
 		}
 	case lsb+width <= 16:
 		shift1 := 64 - (lsb + width)
 		shift2 := 64 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetInt((int64(*(*uint16)(unsafe.Pointer(&b[firstByte]))) << shift1) >> shift2)
+			return nil
 		}
 	case lsb+width <= 24:
 		shift1 := 64 - ((lsb + width) - 16)
 		shift2 := (shift1 + lsb) - 16
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			i := int64(*(*uint16)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetInt(i | ((int64(b[firstByte+2]) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 32:
 		shift1 := 64 - (lsb + width)
 		shift2 := 64 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetInt((int64(*(*uint32)(unsafe.Pointer(&b[firstByte]))) << shift1) >> shift2)
+			return nil
 		}
 
 	case lsb+width <= 40:
 		shift1 := 64 - ((lsb + width) - 32)
 		shift2 := (shift1 + lsb) - 32
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			i := int64(*(*uint32)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetInt(i | ((int64(b[firstByte+4]) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 48:
 		shift1 := 64 - ((lsb + width) - 32)
 		shift2 := (shift1 + lsb) - 32
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			i := int64(*(*uint32)(unsafe.Pointer(&b[firstByte]))) >> lsb
 			field.SetInt(i | ((int64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) << shift1) >> shift2))
+			return nil
 		}
 	case lsb+width <= 56:
 		shift1 := 32 - lsb
 		shift2 := 64 - ((lsb + width) - 48)
 		shift3 := (shift2 + lsb) - 48
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			i := int64(*(*uint32)(unsafe.Pointer(&b[firstByte])) >> lsb)
-			i |= int64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) >> shift1
+			i |= int64(*(*uint16)(unsafe.Pointer(&b[firstByte+4]))) << shift1
 			field.SetInt(i | (int64(b[firstByte+6])<<shift2)>>shift3)
+			return nil
 		}
 	case lsb+width <= 64:
 		shift1 := 64 - (lsb + width)
 		shift2 := 64 - width
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			field.SetInt((*(*int64)(unsafe.Pointer(&b[firstByte])) << shift1) >> shift2)
+			return nil
 		}
 	default:
 		// Assume lsb+width <= 72
 		shift1 := 128 - (lsb + width)
 		shift2 := (shift1 + lsb) - 64
-		return func(b []byte, field reflect.Value) {
+		return func(b []byte, field reflect.Value) error {
+			_ = b[lastByte]
 			i := int64(*(*uint64)(unsafe.Pointer(&b[firstByte])) >> lsb)
 			field.SetInt(i | ((int64(b[firstByte+8]) << shift1) >> shift2))
+			return nil
 		}
 	}
 }
@@ -498,17 +611,19 @@ func makeSignedSingleUnpacker(typ reflect.Type, ilsb uint64, width uint8) unpack
 func makeBoolSinglePacker(lsb uint64) packer {
 	firstByte := lsb / 8
 	tru := byte(1) << uint8(lsb%8)
-	return func(b []byte, field reflect.Value) {
+	return func(b []byte, field reflect.Value) error {
 		if field.Bool() {
 			b[firstByte] |= tru
 		}
+		return nil
 	}
 }
 
 func makeBoolSingleUnpacker(lsb uint64) unpacker {
 	firstByte := lsb / 8
 	tru := byte(1) << uint8(lsb%8)
-	return func(b []byte, field reflect.Value) {
+	return func(b []byte, field reflect.Value) error {
 		field.SetBool(b[firstByte]&tru > 0)
+		return nil
 	}
 }
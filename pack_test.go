@@ -0,0 +1,125 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// Returns a struct type with a uint64 field of
+// prefix bits (if prefix > 0) followed by a
+// uint64 or int64 field of width bits, so that
+// the last field ends at the end of the packed
+// data.
+func tailFieldType(prefix, width int, signed bool) reflect.Type {
+	last := reflect.TypeOf(uint64(0))
+	if signed {
+		last = reflect.TypeOf(int64(0))
+	}
+	var fields []reflect.StructField
+	if prefix > 0 {
+		fields = append(fields, reflect.StructField{
+			Name: "F1",
+			Type: reflect.TypeOf(uint64(0)),
+			Tag:  reflect.StructTag(`gopack:"` + strconv.Itoa(prefix) + `"`),
+		})
+	}
+	fields = append(fields, reflect.StructField{
+		Name: "F2",
+		Type: last,
+		Tag:  reflect.StructTag(`gopack:"` + strconv.Itoa(width) + `"`),
+	})
+	return reflect.StructOf(fields)
+}
+
+// Pack into and unpack from buffers of exactly
+// the packed size carved out of a larger
+// allocation, and make sure that the bytes
+// surrounding the buffer are never touched.
+// Run with -race (which implies -d=checkptr)
+// to have the unsafe accesses checked as well.
+func TestExactBuffers(t *testing.T) {
+	rand.Seed(9930)
+	const canary = 0xA5
+	forEachImpl(t, func(impl string) {
+		for prefix := 0; prefix < 64; prefix++ {
+			for width := 1; width <= 128; width++ {
+				typ := tailFieldType(prefix, (width-1)%64+1, width > 64)
+				n := (prefix + (width-1)%64 + 1 + 7) / 8
+
+				mem := make([]byte, n+16)
+				for i := range mem {
+					mem[i] = canary
+				}
+				b := mem[8 : 8+n : 8+n]
+
+				val := randInstance(typ)
+				for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+					p, _, err := makePackerWrapper(typ, order)
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					u, err := makeUnpackerWrapper(reflect.PtrTo(typ), order)
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					for i := range b {
+						b[i] = 0
+					}
+					if err := p(b, val); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					for i := range mem {
+						if (i < 8 || i >= 8+n) && mem[i] != canary {
+							t.Fatalf("%v, %v: packing %v wrote outside of buffer at offset %v",
+								impl, order, typ, i-8)
+						}
+					}
+					val2 := reflect.New(typ)
+					if err := u(b, val2); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					if val2.Elem().Interface() != val.Interface() {
+						t.Fatalf("%v, %v: expected %v; got %v (on type %v)",
+							impl, order, val.Interface(), val2.Elem().Interface(), typ)
+					}
+				}
+			}
+		}
+	})
+}
+
+// Packers called directly with a buffer which is
+// too short must panic rather than access memory
+// beyond the end of the buffer.
+func TestShortBufferBoundsCheck(t *testing.T) {
+	forEachImpl(t, func(impl string) {
+		for prefix := 0; prefix < 8; prefix++ {
+			for width := 9; width <= 64; width++ {
+				typ := tailFieldType(prefix, width, false)
+				n := (prefix + width + 7) / 8
+				mem := make([]byte, n)
+				p, _, _ := makePackerWrapper(typ, LSBFirst)
+				testPanics(t, impl, typ, func() { p(mem[:n-1:n-1], reflect.New(typ).Elem()) })
+				// Call the unpacker's inner function, skipping
+				// the length check done by the wrapper.
+				inner, _, _ := makeUnpacker(LSBFirst, "", 0, reflect.PtrTo(typ))
+				testPanics(t, impl, typ, func() { inner(mem[:n-1:n-1], reflect.New(typ)) })
+			}
+		}
+	})
+}
+
+func testPanics(t *testing.T, impl string, typ reflect.Type, f func()) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("%v: expected panic for short buffer (on type %v)", impl, typ)
+		}
+	}()
+	f()
+}
@@ -0,0 +1,96 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// Blank fields with a gopack tag are
+// padding; all other blank fields are
+// ignored like other unexported fields.
+func isPadding(field reflect.StructField) bool {
+	return field.Name == "_" && field.Tag.Get("gopack") != ""
+}
+
+type padding struct {
+	width uint64
+	value uint64
+	check bool
+}
+
+func getPadding(path string, lsb uint64, tag reflect.StructTag) (padding, error) {
+	var pad padding
+	t, err := parseTag(path, lsb, tag)
+	if err != nil {
+		return pad, err
+	}
+	if err := t.allow(path, lsb, "pad", "value", "check"); err != nil {
+		return pad, err
+	}
+	if t.width != "" || !t.has("pad") {
+		return pad, newFieldError(BadTag, path, lsb, 0, "bad struct tag: padding must be specified as \"pad=<bits>\"")
+	}
+	n, err := t.getInt(path, lsb, "pad", 0)
+	if err != nil {
+		return pad, err
+	}
+	if n < 1 {
+		return pad, newFieldError(TagTooSmall, path, lsb, 0, "struct tag too small (%d)", n)
+	}
+	pad.width = uint64(n)
+	pad.check = t.has("check")
+	if t.has("value") {
+		if pad.width > 64 {
+			return pad, newFieldError(TagTooWide, path, lsb, pad.width,
+				"struct tag too wide for padding with value (%d)", n)
+		}
+		val, err := t.getUint(path, lsb, "value")
+		if err != nil {
+			return pad, err
+		}
+		if pad.width < 64 && val>>pad.width != 0 {
+			return pad, newFieldError(Overflow, path, lsb, pad.width,
+				"padding value out of range: max %v; got %v", (uint64(1)<<pad.width)-1, val)
+		}
+		pad.value = val
+	}
+	return pad, nil
+}
+
+func makePadPacker(order BitOrder, lsb uint64, pad padding) packer {
+	if pad.value == 0 {
+		// Pack zeroes b before
+		// calling any packers
+		return noOpPacker
+	}
+	put, _ := bitsFuncs(order)
+	return func(b []byte, v reflect.Value) error {
+		put(b, lsb, uint8(pad.width), pad.value)
+		return nil
+	}
+}
+
+func makePadUnpacker(order BitOrder, path string, lsb uint64, pad padding) unpacker {
+	if !pad.check {
+		return noOpUnpacker
+	}
+	_, get := bitsFuncs(order)
+	return func(b []byte, v reflect.Value) error {
+		// Padding wider than 64 bits is always
+		// zero, so check it 64 bits at a time
+		for off := uint64(0); off < pad.width; off += 64 {
+			width := pad.width - off
+			if width > 64 {
+				width = 64
+			}
+			if u := get(b, lsb+off, uint8(width)); u != pad.value {
+				return newFieldError(BadPadding, path, lsb, pad.width,
+					"padding has unexpected value: expected %v; got %v", pad.value, u)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"testing"
+)
+
+func TestPadding(t *testing.T) {
+	type typ struct {
+		F1 uint8    `gopack:"3"`
+		_  struct{} `gopack:"pad=3"`
+		F2 uint8    `gopack:"2"`
+		_  struct{} `gopack:"pad=4,value=0xA"`
+		F3 bool
+		_  uint8 // Ignored
+	}
+
+	if sz := PackedSizeof(typ{}); sz != 2 {
+		t.Errorf("Expected a packed size of 2 but got %d", sz)
+	}
+
+	b := []byte{0xFF, 0xFF}
+	val := typ{F1: 5, F2: 3, F3: true}
+	Pack(b, val)
+	expect := []byte{0xC5, 0x1A}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+
+	var val2 typ
+	Unpack(b, &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	// Without the check option,
+	// padding bits are ignored
+	b = []byte{0xFD, 0x15}
+	Unpack(b, &val2)
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	Config{Order: MSBFirst}.Pack(b, val)
+	expect = []byte{0xA3, 0xA8}
+	if string(b) != string(expect) {
+		t.Fatalf("Expected %v; got %v", expect, b)
+	}
+}
+
+func TestCheckedPadding(t *testing.T) {
+	type header struct {
+		Version uint8    `gopack:"4"`
+		_       struct{} `gopack:"pad=4,check"`
+	}
+	type typ struct {
+		Header header
+		_      struct{} `gopack:"pad=72,check"`
+		Magic  uint8
+		_      struct{} `gopack:"pad=8,value=0x7E,check"`
+	}
+
+	if sz := PackedSizeof(typ{}); sz != 12 {
+		t.Errorf("Expected a packed size of 12 but got %d", sz)
+	}
+
+	b := make([]byte, 12)
+	val := typ{Header: header{Version: 4}, Magic: 0x42}
+	Pack(b, val)
+	var val2 typ
+	if err := UnpackE(b, &val2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val2 != val {
+		t.Fatalf("Expected %v; got %v", val, val2)
+	}
+
+	b[0] |= 0x10
+	testError(t, BadPadding, "gopack: Header._: padding has unexpected value: expected 0; got 1", func() {
+		Unpack(b, &val2)
+	})
+	b[0] &^= 0x10
+	b[9] = 0x80
+	testError(t, BadPadding, "gopack: _: padding has unexpected value: expected 0; got 128", func() {
+		Unpack(b, &val2)
+	})
+	b[9] = 0
+	b[11] = 0
+	err := UnpackE(b, &val2)
+	if e, ok := err.(Error); !ok || e.Kind != BadPadding || e.Offset != 88 || e.Width != 8 {
+		t.Fatalf("Expected padding error at offset 88, width 8; got %#v", err)
+	}
+}
+
+func TestPaddingTagErrors(t *testing.T) {
+	type typ struct {
+		_ struct{} `gopack:"3"`
+	}
+	testError(t, BadTag, "gopack: _: bad struct tag: padding must be specified as \"pad=<bits>\"", func() {
+		Pack(nil, typ{})
+	})
+
+	type typ1 struct {
+		_ struct{} `gopack:"pad=0"`
+	}
+	testError(t, TagTooSmall, "gopack: _: struct tag too small (0)", func() {
+		Pack(nil, typ1{})
+	})
+
+	type typ2 struct {
+		_ struct{} `gopack:"pad=3,value=8"`
+	}
+	testError(t, Overflow, "gopack: _: padding value out of range: max 7; got 8", func() {
+		Unpack(nil, &typ2{})
+	})
+
+	type typ3 struct {
+		F1 uint8 `gopack:"pad=3"`
+	}
+	testError(t, BadTag, "gopack: F1: bad struct tag: unsupported option \"pad\"", func() {
+		Pack(nil, typ3{})
+	})
+}
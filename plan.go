@@ -0,0 +1,460 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// A plan is the layout of a type, computed
+// once by walking the type and its tags, from
+// which the packers, unpackers, and Layouts
+// for the type are all derived. Plans do not
+// depend on the bit order.
+type plan struct {
+	kind planKind
+	// The name, path, and declared type of the
+	// field (see FieldLayout). The root of a
+	// plan has an empty name.
+	name, path string
+	typ        reflect.Type
+	// The absolute position of the field. For
+	// variable-size plans, lsb is always 0 and
+	// bits is the smallest size of the field.
+	lsb, bits uint64
+	// Whether the size of the field depends
+	// on its value (see varlen.go)
+	varSize bool
+
+	float floatEncoding  // planFloat
+	pad   padding        // planPadding
+	str   stringEncoding // planString
+
+	// For structs, the plan for each field,
+	// or nil for fields which are not packed.
+	// For arrays, the plan for each element.
+	fields []*plan
+	// For structs, the ranges not
+	// occupied by any field
+	gaps []BitRange
+	// For variable-size structs, the
+	// fields grouped into segments
+	segs []segment
+
+	// For slices, the plan for each element
+	// (placed at offset 0), and the source
+	// of the length: either a prefix of
+	// lenBits bits, or the earlier sibling
+	// field named lenName, whose index is
+	// lenField.
+	elem     *plan
+	lenBits  uint64
+	lenName  string
+	lenField int
+
+	// For pointers, the plan for the value
+	// pointed to is elem, which is preceded
+	// by a presence bit if optional is set.
+	optional bool
+
+	// For conditional fields, the plan for the
+	// field itself is elem, and it is packed only
+	// if the earlier bool sibling field named
+	// condName, whose index is condField, is set.
+	condName  string
+	condField int
+
+	// For unions, the plan for each variant,
+	// placed at offset 0 and keyed by the value
+	// of the earlier integer sibling field named
+	// switchName, whose index is switchField,
+	// and the key of each variant type.
+	variants    map[int64]*plan
+	variantKeys map[reflect.Type]int64
+	switchName  string
+	switchField int
+}
+
+type planKind int
+
+const (
+	planStruct planKind = iota
+	planArray
+	planUnsigned
+	planSigned
+	planBool
+	planFloat
+	planPadding
+	planCustom
+	planSlice
+	planString
+	planPointer
+	planCond
+	planUnion
+)
+
+type planEntry struct {
+	once sync.Once
+	plan *plan
+	err  error
+}
+
+// Plans for types placed at offset 0,
+// keyed by the (non-pointer) type
+var planCache sync.Map
+
+// Returns the plan for strct, which is either
+// a type which can be passed to Pack or a
+// pointer to one, placed at offset 0.
+func planFor(strct reflect.Type) (*plan, error) {
+	if strct.Kind() == reflect.Ptr {
+		strct = strct.Elem()
+	}
+	v, ok := planCache.Load(strct)
+	if !ok {
+		v, _ = planCache.LoadOrStore(strct, new(planEntry))
+	}
+	entry := v.(*planEntry)
+	entry.once.Do(func() {
+		entry.plan, entry.err = makePlan("", 0, strct)
+	})
+	return entry.plan, entry.err
+}
+
+// Returns the plan for strct placed at lsb,
+// where strct is a struct, a type with a
+// custom encoding, or a pointer to either.
+func makePlan(path string, lsb uint64, strct reflect.Type) (*plan, error) {
+	if strct.Kind() == reflect.Ptr {
+		strct = strct.Elem()
+	}
+	if isCustom(strct) {
+		return makeFieldPlan("", path, lsb, strct, "")
+	}
+	if strct.Kind() != reflect.Struct {
+		return nil, newError(NonStruct, "non-struct type %v", strct.String())
+	}
+	return makeStructPlan("", path, lsb, strct)
+}
+
+func makeStructPlan(name, path string, lsb uint64, strct reflect.Type) (*plan, error) {
+	p := &plan{kind: planStruct, name: name, path: path, typ: strct, lsb: lsb}
+	p.fields = make([]*plan, strct.NumField())
+	pl := placer{base: lsb}
+	// The index of the first field of
+	// the current run (see segment)
+	run := 0
+	for i := range p.fields {
+		field := strct.Field(i)
+		if !isPadding(field) && !isExported(field) {
+			continue
+		}
+		fpath := fieldPath(path, field.Name)
+		flsb, err := pl.start(fpath, field)
+		if err != nil {
+			return nil, err
+		}
+		if p.varSize && flsb != pl.base+pl.next {
+			return nil, newFieldError(BadTag, fpath, flsb, 0, "bad struct tag: offset after variable-size field")
+		}
+		var f *plan
+		if isPadding(field) {
+			f = &plan{kind: planPadding, name: field.Name, path: fpath, typ: field.Type, lsb: flsb}
+			f.pad, err = getPadding(fpath, flsb, field.Tag)
+			f.bits = f.pad.width
+		} else {
+			f, err = makeMemberPlan(field.Name, fpath, flsb, field.Type, field.Tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.varSize {
+			if flsb != pl.base+pl.next {
+				return nil, newFieldError(BadTag, fpath, flsb, 0, "bad struct tag: offset on variable-size field")
+			}
+			if err := p.resolveField(f, i); err != nil {
+				return nil, err
+			}
+			// Close the current run, and start
+			// a new one after the field
+			if run < i {
+				p.segs = append(p.segs, segment{start: run, end: i, bits: pl.end})
+			}
+			p.segs = append(p.segs, segment{start: i, end: i + 1, varField: true})
+			p.bits += pl.end + f.bits
+			p.varSize = true
+			p.fields[i] = f
+			pl = placer{}
+			run = i + 1
+			continue
+		}
+		if err := pl.add(fpath, flsb, f.bits); err != nil {
+			return nil, err
+		}
+		p.fields[i] = f
+	}
+	if p.varSize {
+		if lsb != 0 {
+			// Variable-size plans are always placed
+			// at offset 0 (see plan)
+			return makeStructPlan(name, path, 0, strct)
+		}
+		if run < len(p.fields) {
+			p.segs = append(p.segs, segment{start: run, end: len(p.fields), bits: pl.end})
+			p.bits += pl.end
+		}
+		return p, nil
+	}
+	p.bits = pl.end
+	p.gaps = pl.gaps()
+	return p, nil
+}
+
+// Returns the plan for a field of a struct,
+// which, unlike array elements and the like,
+// may be conditional or a union, since these
+// refer to the field's siblings.
+func makeMemberPlan(name, path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	switch {
+	case isConditional(tag):
+		return makeCondPlan(name, path, typ, tag)
+	case typ.Kind() == reflect.Interface:
+		return makeUnionPlan(name, path, typ, tag)
+	}
+	return makeFieldPlan(name, path, lsb, typ, tag)
+}
+
+// Resolves the options of f, which is field
+// i of p, which refer to earlier fields.
+func (p *plan) resolveField(f *plan, i int) error {
+	switch f.kind {
+	case planCond:
+		return p.resolveCond(f, i)
+	case planUnion:
+		return p.resolveSwitch(f, i)
+	}
+	return p.resolveLength(f, i)
+}
+
+func makeFieldPlan(name, path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	p := &plan{name: name, path: path, typ: typ, lsb: lsb}
+	var err error
+	if isCustom(typ) {
+		// The width is checked here, but whether
+		// typ implements both BitPacker and
+		// BitUnpacker is only checked when
+		// packing or unpacking respectively
+		iface := bitPackerType
+		if !typ.Implements(iface) && !reflect.PtrTo(typ).Implements(iface) {
+			iface = bitUnpackerType
+		}
+		p.kind = planCustom
+		p.bits, err = getCustomWidth(path, lsb, typ, iface, tag)
+		return p, err
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		p.kind = planSigned
+		p.bits, err = getFieldWidth(path, lsb, typ, tag)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		p.kind = planUnsigned
+		p.bits, err = getFieldWidth(path, lsb, typ, tag)
+	case reflect.Bool:
+		p.kind = planBool
+		p.bits = 1
+	case reflect.Float32, reflect.Float64:
+		p.kind = planFloat
+		p.float, err = getFloatEncoding(path, lsb, typ, tag)
+		p.bits = uint64(p.float.width)
+	case reflect.Struct:
+		return makeStructPlan(name, path, lsb, typ)
+	case reflect.Array:
+		// The tag applies to each element
+		p.kind = planArray
+		p.fields = make([]*plan, typ.Len())
+		for i := range p.fields {
+			ename := "[" + strconv.Itoa(i) + "]"
+			f, err := makeFieldPlan(ename, elemPath(path, i), lsb+p.bits, typ.Elem(), tag)
+			if err != nil {
+				return nil, err
+			}
+			if f.lenName != "" {
+				return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: lenfield on array elements")
+			}
+			p.fields[i] = f
+			p.bits += f.bits
+			p.varSize = f.varSize
+		}
+		if p.varSize {
+			p.lsb = 0
+		}
+	case reflect.String:
+		return makeStringPlan(name, path, lsb, typ, tag)
+	case reflect.Slice:
+		if isByteString(typ, tag) {
+			return makeStringPlan(name, path, lsb, typ, tag)
+		}
+		return makeSlicePlan(name, path, typ, tag)
+	case reflect.Ptr:
+		return makePointerPlan(name, path, lsb, typ, tag)
+	default:
+		err = newFieldError(UnsupportedType, path, lsb, 0, "non-packable type %v", typ.String())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Returns a packer for the type of p, or
+// for a pointer to it if ptrType is set.
+func (p *plan) packer(order BitOrder, ptrType bool) (packer, error) {
+	if ptrType && p.kind != planStruct {
+		f, err := p.packer(order, false)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) error { return f(b, v.Elem()) }, nil
+	}
+	switch p.kind {
+	case planStruct, planArray:
+		packers := make([]packer, len(p.fields))
+		for i, f := range p.fields {
+			if f == nil {
+				packers[i] = noOpPacker
+				continue
+			}
+			var err error
+			if packers[i], err = f.packer(order, false); err != nil {
+				return nil, err
+			}
+		}
+		if p.kind == planArray {
+			return makeCallAllElemPackers(packers), nil
+		}
+		return makeCallAllPackers(packers, ptrType), nil
+	case planUnsigned:
+		return makeUnsignedPacker(order, p.path, p.typ, p.lsb, uint8(p.bits)), nil
+	case planSigned:
+		return makeSignedPacker(order, p.path, p.typ, p.lsb, uint8(p.bits)), nil
+	case planBool:
+		return makeBoolPacker(order, p.lsb), nil
+	case planFloat:
+		return makeFloatPacker(order, p.path, p.lsb, p.float), nil
+	case planPadding:
+		return makePadPacker(order, p.lsb, p.pad), nil
+	case planString:
+		return makeStringPacker(order, p.path, p.lsb, p.str), nil
+	case planPointer:
+		elem, err := p.elem.packer(order, false)
+		if err != nil {
+			return nil, err
+		}
+		return makePointerPacker(p, elem), nil
+	default:
+		return makeCustomPacker(order, p.path, p.lsb, p.typ, p.bits)
+	}
+}
+
+// Returns an unpacker for the type of p, or
+// for a pointer to it if ptrType is set.
+func (p *plan) unpacker(order BitOrder, ptrType bool) (unpacker, error) {
+	if ptrType && p.kind != planStruct {
+		f, err := p.unpacker(order, false)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, v reflect.Value) error { return f(b, v.Elem()) }, nil
+	}
+	switch p.kind {
+	case planStruct, planArray:
+		unpackers := make([]unpacker, len(p.fields))
+		for i, f := range p.fields {
+			if f == nil {
+				unpackers[i] = noOpUnpacker
+				continue
+			}
+			var err error
+			if unpackers[i], err = f.unpacker(order, false); err != nil {
+				return nil, err
+			}
+		}
+		if p.kind == planArray {
+			return makeCallAllElemUnpackers(unpackers), nil
+		}
+		return makeCallAllUnpackers(unpackers, ptrType), nil
+	case planUnsigned:
+		return makeUnsignedUnpacker(order, p.typ, p.lsb, uint8(p.bits)), nil
+	case planSigned:
+		return makeSignedUnpacker(order, p.typ, p.lsb, uint8(p.bits)), nil
+	case planBool:
+		return makeBoolUnpacker(order, p.lsb), nil
+	case planFloat:
+		return makeFloatUnpacker(order, p.lsb, p.float), nil
+	case planPadding:
+		return makePadUnpacker(order, p.path, p.lsb, p.pad), nil
+	case planString:
+		return makeStringUnpacker(order, p.lsb, p.str), nil
+	case planPointer:
+		elem, err := p.elem.unpacker(order, false)
+		if err != nil {
+			return nil, err
+		}
+		return makePointerUnpacker(elem), nil
+	default:
+		return makeCustomUnpacker(order, p.path, p.lsb, p.typ, p.bits)
+	}
+}
+
+// Returns the layout of the field
+// described by p.
+func (p *plan) layout() FieldLayout {
+	if p.kind == planPointer {
+		// A pointer is laid out as
+		// the value it points to
+		f := p.elem.layout()
+		f.GoType = p.typ
+		return f
+	}
+	f := FieldLayout{
+		Name:      p.name,
+		Path:      p.path,
+		GoType:    p.typ,
+		BitOffset: p.lsb,
+		BitWidth:  p.bits,
+		Signed:    p.kind == planSigned || (p.kind == planFloat && p.float.signed),
+	}
+	switch p.kind {
+	case planStruct:
+		for _, c := range p.fields {
+			if c != nil {
+				f.Children = append(f.Children, c.layout())
+			}
+		}
+	case planArray:
+		f.Children = make([]FieldLayout, len(p.fields))
+		for i, c := range p.fields {
+			f.Children[i] = c.layout()
+		}
+	}
+	return f
+}
+
+// Returns the gaps within p and
+// any structs nested within it.
+func (p *plan) allGaps() []BitRange {
+	if p.kind == planPointer {
+		return p.elem.allGaps()
+	}
+	gaps := append([]BitRange(nil), p.gaps...)
+	for _, c := range p.fields {
+		if c != nil {
+			gaps = append(gaps, c.allGaps()...)
+		}
+	}
+	return gaps
+}
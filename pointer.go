@@ -0,0 +1,174 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// A pointer field is packed as the value it
+// points to. With the "optional" option, it
+// is preceded by a presence bit, and a nil
+// pointer is packed as only that bit, which
+// makes the field variable-size.
+func makePointerPlan(name, path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	t, err := parseTag(path, lsb, tag)
+	if err != nil {
+		return nil, err
+	}
+	if isRecursive(typ.Elem()) {
+		return nil, newFieldError(UnsupportedType, path, lsb, 0, "recursive type %v", typ.Elem())
+	}
+	p := &plan{kind: planPointer, name: name, path: path, typ: typ, lsb: lsb, lenField: -1, optional: t.has("optional")}
+	if p.optional {
+		if t.opts["optional"] != "" {
+			return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option \"optional\" takes no value")
+		}
+		lsb = 0
+	}
+	// The rest of the tag applies to the pointee
+	p.elem, err = makeFieldPlan(name, path, lsb, typ.Elem(), withoutOptions(tag, "optional"))
+	if err != nil {
+		return nil, err
+	}
+	if p.elem.lenName != "" {
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: lenfield on pointer")
+	}
+	p.bits = p.elem.bits
+	p.varSize = p.elem.varSize
+	if p.optional {
+		p.bits, p.varSize = 1, true
+	}
+	if p.varSize {
+		p.lsb = 0
+	}
+	return p, nil
+}
+
+// Reports whether a value of type typ can
+// contain another value of type typ, through
+// pointers, slices, or the variants of
+// unions. Such types cannot be packed.
+func isRecursive(typ reflect.Type) bool {
+	return containsType(typ, typ, make(map[reflect.Type]bool))
+}
+
+func containsType(t, target reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] || isCustom(t) {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return t.Elem() == target || containsType(t.Elem(), target, seen)
+	case reflect.Interface:
+		for _, vt := range variantTypes(t) {
+			if vt == target || containsType(vt, target, seen) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if isExported(f) && (f.Type == target || containsType(f.Type, target, seen)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *plan) nilError(off uint64) error {
+	return newFieldError(NilPointer, p.path, off, p.bits, "nil pointer without option \"optional\"")
+}
+
+// Sets v to point to a new value
+// if it is nil, and returns the
+// value it points to.
+func allocElem(v reflect.Value) reflect.Value {
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	return v.Elem()
+}
+
+func makePointerPacker(p *plan, elem packer) packer {
+	return func(b []byte, v reflect.Value) error {
+		if v.IsNil() {
+			return p.nilError(p.lsb)
+		}
+		return elem(b, v.Elem())
+	}
+}
+
+func makePointerUnpacker(elem unpacker) unpacker {
+	return func(b []byte, v reflect.Value) error {
+		return elem(b, allocElem(v))
+	}
+}
+
+func (p *plan) pointerVarPacker(order BitOrder) (varPacker, error) {
+	elem, err := p.elem.varPacker(order)
+	if err != nil {
+		return nil, err
+	}
+	if !p.optional {
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			if v.IsNil() {
+				return 0, p.nilError(off)
+			}
+			return elem(b, off, v.Elem())
+		}, nil
+	}
+	put, _ := bitsFuncs(order)
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		if v.IsNil() {
+			return off + 1, nil
+		}
+		put(b, off, 1, 1)
+		return elem(b, off+1, v.Elem())
+	}, nil
+}
+
+func (p *plan) pointerVarUnpacker(order BitOrder) (varUnpacker, error) {
+	elem, err := p.elem.varUnpacker(order)
+	if err != nil {
+		return nil, err
+	}
+	if !p.optional {
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			return elem(b, off, allocElem(v))
+		}, nil
+	}
+	_, get := bitsFuncs(order)
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		if need := bitsToBytes(off + 1); len(b) < need {
+			return 0, newShortBufferError(p.path, off, 1, need, "buffer too small (%v; need %v)", len(b), need)
+		}
+		if get(b, off, 1) == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return off + 1, nil
+		}
+		return elem(b, off+1, allocElem(v))
+	}, nil
+}
+
+// Returns the number of bits occupied by
+// the pointer field v when packed.
+func (p *plan) pointerSize(v reflect.Value) uint64 {
+	switch {
+	case !v.IsNil():
+		n := p.elem.size(v.Elem())
+		if p.optional {
+			n++
+		}
+		return n
+	case p.optional:
+		return 1
+	default:
+		// Packing will fail
+		return p.elem.bits
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type ptrExt struct {
+	A uint8 `gopack:"5"`
+	B bool
+}
+
+type ptrFixed struct {
+	Flag bool
+	Ext  *ptrExt
+	N    *uint16 `gopack:"12"`
+}
+
+type ptrOptional struct {
+	A   uint8   `gopack:"3"`
+	Ext *ptrExt `gopack:"optional"`
+	N   *uint16 `gopack:"12,optional"`
+	B   bool
+}
+
+func TestPointerFields(t *testing.T) {
+	l := LayoutOf(ptrFixed{})
+	if l.Bits != 19 || l.Fields[1].GoType != reflect.TypeOf(&ptrExt{}) ||
+		l.Fields[1].BitOffset != 1 || len(l.Fields[1].Children) != 2 || l.Fields[2].BitWidth != 12 {
+		t.Errorf("Unexpected layout %+v", l)
+	}
+
+	n := uint16(0xABC)
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		expect := make([]byte, 3)
+		w := NewBitWriter(expect, order)
+		w.WriteBool(true)
+		w.WriteUnsigned(21, 5)
+		w.WriteBool(true)
+		w.WriteUnsigned(0xABC, 12)
+		testVarLen(t, c, ptrFixed{true, &ptrExt{21, true}, &n}, &ptrFixed{}, expect)
+
+		expect = make([]byte, 3)
+		w = NewBitWriter(expect, order)
+		w.WriteUnsigned(5, 3)
+		w.WriteBool(true)
+		w.WriteUnsigned(21, 5)
+		w.WriteBool(true)
+		w.WriteBool(false)
+		w.WriteBool(true)
+		testVarLen(t, c, ptrOptional{5, &ptrExt{21, true}, nil, true}, &ptrOptional{}, expect[:2])
+
+		expect = make([]byte, 3)
+		w = NewBitWriter(expect, order)
+		w.WriteUnsigned(5, 3)
+		w.WriteBool(false)
+		w.WriteBool(true)
+		w.WriteUnsigned(0xABC, 12)
+		w.WriteBool(true)
+		testVarLen(t, c, ptrOptional{5, nil, &n, true}, &ptrOptional{}, expect)
+	}
+
+	// Unpack allocates nil pointers, reuses
+	// others, and clears optional ones
+	b := AppendPack(nil, ptrFixed{Ext: &ptrExt{A: 3}, N: &n})
+	var v ptrFixed
+	Unpack(b, &v)
+	if v.Ext == nil || *v.Ext != (ptrExt{A: 3}) || v.N == nil || *v.N != n {
+		t.Errorf("Unexpected value %+v", v)
+	}
+	ext := v.Ext
+	Unpack(b, &v)
+	if v.Ext != ext {
+		t.Errorf("Expected the pointer to be reused")
+	}
+	o := ptrOptional{Ext: &ptrExt{}, N: &n}
+	Unpack([]byte{0, 0}, &o)
+	if o.Ext != nil || o.N != nil {
+		t.Errorf("Expected nil pointers; got %+v", o)
+	}
+}
+
+func TestPointerErrors(t *testing.T) {
+	err := PackE(make([]byte, 3), ptrFixed{N: new(uint16)})
+	if e, ok := err.(Error); !ok || e.Path != "Ext" || e.Offset != 1 || e.Width != 6 || !errors.Is(err, NilPointer) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	err = PackE(make([]byte, 3), ptrOptional{Ext: &ptrExt{A: 32}})
+	if e, ok := err.(Error); !ok || e.Path != "Ext.A" || e.Offset != 4 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	testError(t, ShortBuffer, "gopack: P: buffer too small (1; need 2)", func() {
+		Unpack([]byte{0}, &struct {
+			A uint8
+			P *uint8 `gopack:"optional"`
+		}{})
+	})
+
+	type node struct {
+		Val  uint8
+		Next *node `gopack:"optional"`
+	}
+	testError(t, UnsupportedType, "gopack: Next: recursive type gopack.node", func() {
+		Pack(nil, node{})
+	})
+	type tree struct {
+		Kids []tree `gopack:"len=4"`
+	}
+	testError(t, UnsupportedType, "gopack: Kids: recursive type gopack.tree", func() {
+		Pack(nil, tree{})
+	})
+	testError(t, BadTag, "gopack: P: bad struct tag: option \"optional\" takes no value", func() {
+		Pack(nil, struct {
+			P *uint8 `gopack:"optional=1"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: P: bad struct tag: unsupported option \"optional\"", func() {
+		Pack(nil, struct {
+			P uint8 `gopack:"optional"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: lenfield on pointer", func() {
+		Pack(nil, struct {
+			N uint8
+			S *[]uint8 `gopack:"lenfield=N"`
+		}{})
+	})
+	testError(t, UnsupportedType, "gopack: variable-size type gopack.ptrOptional has no fixed layout", func() {
+		LayoutOf(ptrOptional{})
+	})
+}
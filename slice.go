@@ -0,0 +1,221 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+var (
+	packerType   = reflect.TypeOf((*Packer)(nil)).Elem()
+	unpackerType = reflect.TypeOf((*Unpacker)(nil)).Elem()
+)
+
+// PackSlice packs the elements of the slice s into
+// b, one after another, with each element starting
+// on a byte boundary. The elements are subject to
+// the same restrictions as the argument to Pack,
+// and b must be at least PackedSliceSizeof(s) bytes
+// long, or else PackSlice will panic.
+//
+// PackSlice is equivalent to calling Pack on each
+// element, but looks up the type of the elements
+// only once. To pack the elements without aligning
+// them to byte boundaries, see Config.
+func PackSlice(b []byte, s interface{}) {
+	Config{}.PackSlice(b, s)
+}
+
+// PackSliceE is like PackSlice, except that instead
+// of panicking, it returns any error encountered.
+// All errors returned are of type Error, and errors
+// concerning a particular element have a Path and
+// Offset relative to the start of the slice.
+func PackSliceE(b []byte, s interface{}) error {
+	return Config{}.PackSliceE(b, s)
+}
+
+// UnpackSlice unpacks len(s) elements from b into
+// the slice s. It is the inverse of PackSlice.
+func UnpackSlice(b []byte, s interface{}) {
+	Config{}.UnpackSlice(b, s)
+}
+
+// UnpackSliceE is like UnpackSlice, except that
+// instead of panicking, it returns any error
+// encountered. All errors returned are of type
+// Error.
+func UnpackSliceE(b []byte, s interface{}) error {
+	return Config{}.UnpackSliceE(b, s)
+}
+
+// PackedSliceSizeof returns the number of bytes
+// needed to pack the elements of the slice s. If
+// the type of s cannot be packed, PackedSliceSizeof
+// will panic.
+func PackedSliceSizeof(s interface{}) int {
+	return Config{}.PackedSliceSizeof(s)
+}
+
+// PackSlice is like the package-level
+// PackSlice, but uses the options in c.
+func (c Config) PackSlice(b []byte, s interface{}) {
+	if err := c.PackSliceE(b, s); err != nil {
+		panic(err)
+	}
+}
+
+// PackSliceE is like the package-level
+// PackSliceE, but uses the options in c.
+func (c Config) PackSliceE(b []byte, s interface{}) error {
+	v := reflect.ValueOf(s)
+	p, err := c.slicePacker(v)
+	if err != nil {
+		return err
+	}
+	n := v.Len()
+	bytes := c.sliceBytes(p.bits, p.bytes, n)
+	if len(b) < bytes {
+		return newError(ShortBuffer, "buffer too small (%v; need %v)", len(b), bytes)
+	}
+	for i := 0; i < bytes; i++ {
+		b[i] = 0
+	}
+
+	// Use generated methods if there are any,
+	// as Pack would
+	elem := v.Type().Elem()
+	gen := elem.Kind() == reflect.Struct && elem.Implements(packerType) &&
+		generatedFor(reflect.Zero(elem).Interface().(Packer), elem, c.Order)
+	pack := func(b []byte, i int) error {
+		if gen {
+			return v.Index(i).Interface().(Packer).PackBits(b)
+		}
+		return p.packer(b, v.Index(i))
+	}
+
+	var scratch []byte
+	for i := 0; i < n; i++ {
+		off, phase := c.sliceOffset(p.bits, p.bytes, i)
+		if phase == 0 {
+			err = pack(b[off:], i)
+		} else {
+			// Pack into scratch, and then shift the
+			// bits into place after the last element
+			scratch = growZero(scratch[:0], p.bytes)
+			if err = pack(scratch, i); err == nil {
+				shiftInBits(c.Order, b[off:bytes], phase, scratch)
+			}
+		}
+		if err != nil {
+			return sliceError(err, i, 8*uint64(off)+uint64(phase))
+		}
+	}
+	return nil
+}
+
+// UnpackSlice is like the package-level
+// UnpackSlice, but uses the options in c.
+func (c Config) UnpackSlice(b []byte, s interface{}) {
+	if err := c.UnpackSliceE(b, s); err != nil {
+		panic(err)
+	}
+}
+
+// UnpackSliceE is like the package-level
+// UnpackSliceE, but uses the options in c.
+func (c Config) UnpackSliceE(b []byte, s interface{}) error {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Slice {
+		return newError(NonStruct, "non-slice type %v", v.Type())
+	}
+	ptr := reflect.PtrTo(v.Type().Elem())
+	u := unpackerFor(ptr, c.Order)
+	if u.err != nil {
+		return u.err
+	}
+	if u.variable {
+		return newError(UnsupportedType, "slice of variable-size type %v", v.Type().Elem())
+	}
+	n := v.Len()
+	bytes := c.sliceBytes(u.bits, u.bytes, n)
+	if len(b) < bytes {
+		return newError(ShortBuffer, "buffer too small (%v; need %v)", len(b), bytes)
+	}
+
+	gen := ptr.Implements(unpackerType) &&
+		generatedFor(reflect.New(v.Type().Elem()).Interface().(Unpacker), ptr, c.Order)
+	unpack := func(b []byte, i int) error {
+		if gen {
+			return v.Index(i).Addr().Interface().(Unpacker).UnpackBits(b)
+		}
+		return u.unpacker(b, v.Index(i).Addr())
+	}
+
+	var scratch []byte
+	for i := 0; i < n; i++ {
+		off, phase := c.sliceOffset(u.bits, u.bytes, i)
+		var err error
+		if phase == 0 {
+			err = unpack(b[off:], i)
+		} else {
+			scratch = growZero(scratch[:0], u.bytes)
+			shiftOutBits(c.Order, scratch, phase, b[off:bytes])
+			err = unpack(scratch, i)
+		}
+		if err != nil {
+			return sliceError(err, i, 8*uint64(off)+uint64(phase))
+		}
+	}
+	return nil
+}
+
+// PackedSliceSizeof is like the package-level
+// PackedSliceSizeof, but uses the options in c.
+func (c Config) PackedSliceSizeof(s interface{}) int {
+	v := reflect.ValueOf(s)
+	p, err := c.slicePacker(v)
+	if err != nil {
+		panic(err)
+	}
+	return c.sliceBytes(p.bits, p.bytes, v.Len())
+}
+
+func (c Config) slicePacker(v reflect.Value) (cachedPacker, error) {
+	if v.Kind() != reflect.Slice {
+		return cachedPacker{}, newError(NonStruct, "non-slice type %v", v.Type())
+	}
+	p := packerFor(v.Type().Elem(), c.Order)
+	if p.err == nil && p.size != nil {
+		return p, newError(UnsupportedType, "slice of variable-size type %v", v.Type().Elem())
+	}
+	return p, p.err
+}
+
+// Returns the number of bytes needed to pack
+// n elements of the given size
+func (c Config) sliceBytes(bits uint64, bytes, n int) int {
+	if c.Contiguous {
+		return bitsToBytes(bits * uint64(n))
+	}
+	return bytes * n
+}
+
+// Returns the byte offset of element i, and the
+// bit offset of the element within that byte
+func (c Config) sliceOffset(bits uint64, bytes, i int) (int, uint8) {
+	if c.Contiguous {
+		off := bits * uint64(i)
+		return int(off / 8), uint8(off % 8)
+	}
+	return bytes * i, 0
+}
+
+// Returns err, returned while packing or
+// unpacking element i of a slice, with a
+// path and offset relative to the slice.
+func sliceError(err error, i int, off uint64) error {
+	return relocateError(err, elemPath("", i), off)
+}
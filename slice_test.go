@@ -0,0 +1,107 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPackSlice(t *testing.T) {
+	r := rand.New(rand.NewSource(8213))
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		for _, contiguous := range []bool{false, true} {
+			c := Config{Order: order, Contiguous: contiguous}
+			for _, n := range []int{0, 1, 7, 100} {
+				vals := make([]twelve, n)
+				for i := range vals {
+					vals[i] = twelve{uint8(r.Intn(32)), int8(r.Intn(128) - 64)}
+				}
+
+				expect := 2 * n
+				if contiguous {
+					expect = (12*n + 7) / 8
+				}
+				if sz := c.PackedSliceSizeof(vals); sz != expect {
+					t.Errorf("%v: Expected a packed size of %v but got %v", c, expect, sz)
+				}
+
+				// The result should match encoding
+				// each element in turn
+				var buf bytes.Buffer
+				e := c.NewEncoder(&buf)
+				for _, v := range vals {
+					e.Encode(v)
+				}
+				e.Flush()
+				b := make([]byte, expect+1)
+				for i := range b {
+					b[i] = 0xFF
+				}
+				c.PackSlice(b, vals)
+				if !bytes.Equal(b[:expect], buf.Bytes()) || b[expect] != 0xFF {
+					t.Errorf("%v: Expected %v; got %v", c, buf.Bytes(), b)
+				}
+
+				vals2 := make([]twelve, n)
+				c.UnpackSlice(b, vals2)
+				if !reflect.DeepEqual(vals, vals2) {
+					t.Errorf("%v: Expected %v; got %v", c, vals, vals2)
+				}
+			}
+		}
+	}
+}
+
+func TestPackSliceCustom(t *testing.T) {
+	vals := []stamp{stampEpoch + 1, stampEpoch + 2, stampEpoch + 3}
+	c := Config{Contiguous: true}
+	b := make([]byte, c.PackedSliceSizeof(vals))
+	if len(b) != 8 {
+		t.Fatalf("Expected a packed size of 8 but got %v", len(b))
+	}
+	c.PackSlice(b, vals)
+	expect := []byte{1, 0, 0x20, 0, 0, 3, 0, 0}
+	if !bytes.Equal(b, expect) {
+		t.Errorf("Expected %v; got %v", expect, b)
+	}
+	vals2 := make([]stamp, 3)
+	c.UnpackSlice(b, vals2)
+	if !reflect.DeepEqual(vals, vals2) {
+		t.Errorf("Expected %v; got %v", vals, vals2)
+	}
+}
+
+func TestPackSliceErrors(t *testing.T) {
+	testError(t, NonStruct, "gopack: non-slice type gopack.twelve", func() {
+		PackSlice(nil, twelve{})
+	})
+	testError(t, NonStruct, "gopack: non-slice type *[]gopack.twelve", func() {
+		UnpackSlice(nil, &[]twelve{})
+	})
+	testError(t, NonStruct, "gopack: non-struct type int", func() {
+		PackedSliceSizeof([]int{})
+	})
+	testError(t, ShortBuffer, "gopack: buffer too small (3; need 4)", func() {
+		PackSlice(make([]byte, 3), make([]twelve, 2))
+	})
+	testError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", func() {
+		Config{Contiguous: true}.UnpackSlice(make([]byte, 2), make([]twelve, 2))
+	})
+
+	vals := make([]twelve, 3)
+	vals[2].B = 64
+	err := Config{Contiguous: true}.PackSliceE(make([]byte, 5), vals)
+	if e, ok := err.(Error); !ok || e.Path != "[2].B" || e.Offset != 29 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	err = PackSliceE(make([]byte, 9), make([]stamp, 3))
+	if e, ok := err.(Error); !ok || e.Path != "[0]" || e.Offset != 0 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+}
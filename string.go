@@ -0,0 +1,238 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+)
+
+// A charset maps the characters first through
+// first+2^width-1 to the codes 0 through
+// 2^width-1.
+type charset struct {
+	name  string
+	width uint8
+	first byte
+}
+
+var (
+	byteCharset = charset{"byte", 8, 0}
+	charsets    = map[string]charset{
+		"ascii7": {"ascii7", 7, 0},
+		// DEC SIXBIT, which covers the
+		// printable ASCII characters from
+		// space to underscore
+		"sixbit": {"sixbit", 6, ' '},
+	}
+)
+
+func (c charset) encode(b byte) (uint64, bool) {
+	u := uint64(b) - uint64(c.first)
+	return u, b >= c.first && u < 1<<c.width
+}
+
+func (c charset) decode(u uint64) byte {
+	return c.first + byte(u)
+}
+
+// stringEncoding describes how a string or
+// []byte field is stored: as a sequence of
+// characters in a charset, either n of them,
+// padded with fill, or a variable number.
+type stringEncoding struct {
+	charset charset
+	n       int
+	fill    byte
+}
+
+// Reports whether a []byte field with the
+// given tag is packed as a string rather
+// than as a slice of uint8.
+func isByteString(typ reflect.Type, tag reflect.StructTag) bool {
+	if typ.Kind() != reflect.Slice || typ.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	t, err := parseTag("", 0, tag)
+	// Let makeStringPlan report the error
+	return err != nil || t.has("bytes") || t.has("charset") || t.has("fill")
+}
+
+func makeStringPlan(name, path string, lsb uint64, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	p := &plan{kind: planString, name: name, path: path, typ: typ, lsb: lsb, lenField: -1}
+	t, err := parseTag(path, lsb, tag)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.allow(path, lsb, "bytes", "len", "lenfield", "charset", "fill"); err != nil {
+		return nil, err
+	}
+	if t.width != "" {
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: width given for %v field", typ)
+	}
+
+	enc := stringEncoding{charset: byteCharset}
+	if t.has("charset") {
+		cs, ok := charsets[t.opts["charset"]]
+		if !ok {
+			return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: unknown charset %q", t.opts["charset"])
+		}
+		enc.charset = cs
+	}
+	if _, ok := enc.charset.encode(0); !ok {
+		enc.fill = ' '
+	}
+
+	n := 0
+	for _, key := range []string{"bytes", "len", "lenfield"} {
+		if t.has(key) {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: exactly one of \"bytes\", \"len\", and \"lenfield\" is required")
+	}
+	if t.has("fill") && !t.has("bytes") {
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option \"fill\" requires \"bytes\"")
+	}
+	switch t.opts["fill"] {
+	case "":
+	case "nul":
+		enc.fill = 0
+	case "space":
+		enc.fill = ' '
+	default:
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: unknown fill %q", t.opts["fill"])
+	}
+	if _, ok := enc.charset.encode(enc.fill); !ok {
+		return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: fill %q not in charset %s", enc.fill, enc.charset.name)
+	}
+
+	switch {
+	case t.has("bytes"):
+		if enc.n, err = t.getInt(path, lsb, "bytes", 0); err != nil {
+			return nil, err
+		}
+		if enc.n < 1 {
+			return nil, newFieldError(BadTag, path, lsb, 0, "bad struct tag: string length too small (%d)", enc.n)
+		}
+		p.bits = uint64(enc.n) * uint64(enc.charset.width)
+	case t.has("len"):
+		if p.lenBits, err = getLengthBits(path, t); err != nil {
+			return nil, err
+		}
+		p.lsb, p.bits, p.varSize = 0, p.lenBits, true
+	default:
+		p.lenName = t.opts["lenfield"]
+		p.lsb, p.varSize = 0, true
+	}
+	p.str = enc
+	return p, nil
+}
+
+// Returns the string held in v, which
+// is a string or a []byte.
+func stringOf(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return string(v.Bytes())
+}
+
+// Sets v, which is a string or a []byte, to
+// the characters in s, reusing the storage
+// of a []byte if it has enough capacity.
+func setString(v reflect.Value, s []byte) {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(s))
+	case v.Cap() >= len(s):
+		v.SetLen(len(s))
+		copy(v.Bytes(), s)
+	default:
+		v.SetBytes(s)
+	}
+}
+
+// Packs s at off, followed by fill characters
+// up to n characters in total.
+func (e stringEncoding) pack(put putBitsFunc, path string, b []byte, off uint64, s string, n int) error {
+	w := uint64(e.charset.width)
+	for i := 0; i < n; i++ {
+		c := e.fill
+		if i < len(s) {
+			c = s[i]
+		}
+		u, ok := e.charset.encode(c)
+		if !ok {
+			return newFieldError(Unrepresentable, path, off, w, "character %q not in charset %s", c, e.charset.name)
+		}
+		put(b, off, uint8(w), u)
+		off += w
+	}
+	return nil
+}
+
+// Unpacks n characters at off.
+func (e stringEncoding) unpack(get getBitsFunc, b []byte, off uint64, n int) []byte {
+	w := uint64(e.charset.width)
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = e.charset.decode(get(b, off, uint8(w)))
+		off += w
+	}
+	return s
+}
+
+func makeStringPacker(order BitOrder, path string, lsb uint64, enc stringEncoding) packer {
+	put, _ := bitsFuncs(order)
+	return func(b []byte, v reflect.Value) error {
+		s := stringOf(v)
+		if len(s) > enc.n {
+			return newFieldError(Overflow, path, lsb, uint64(enc.n)*uint64(enc.charset.width),
+				"string too long: max %v; got %v", enc.n, len(s))
+		}
+		return enc.pack(put, path, b, lsb, s, enc.n)
+	}
+}
+
+func makeStringUnpacker(order BitOrder, lsb uint64, enc stringEncoding) unpacker {
+	_, get := bitsFuncs(order)
+	return func(b []byte, v reflect.Value) error {
+		s := enc.unpack(get, b, lsb, enc.n)
+		for len(s) > 0 && s[len(s)-1] == enc.fill {
+			s = s[:len(s)-1]
+		}
+		setString(v, s)
+		return nil
+	}
+}
+
+// Returns a varPacker which packs the characters
+// of a variable-length string, without its length.
+func (p *plan) stringBodyPacker(order BitOrder) varPacker {
+	put, _ := bitsFuncs(order)
+	w := uint64(p.str.charset.width)
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		s := stringOf(v)
+		if err := p.str.pack(put, p.path, b, off, s, len(s)); err != nil {
+			return 0, err
+		}
+		return off + uint64(len(s))*w, nil
+	}
+}
+
+// The inverse of stringBodyPacker
+func (p *plan) stringBodyUnpacker(order BitOrder) func(b []byte, off uint64, v reflect.Value, n uint64) (uint64, error) {
+	_, get := bitsFuncs(order)
+	w := uint64(p.str.charset.width)
+	return func(b []byte, off uint64, v reflect.Value, n uint64) (uint64, error) {
+		if n > (uint64(len(b))*8-off)/w {
+			need := bytesFor(off, n, w)
+			return 0, newShortBufferError(p.path, off, 0, need, "buffer too small (%v) for %v characters", len(b), n)
+		}
+		setString(v, p.str.unpack(get, b, off, int(n)))
+		return off + n*w, nil
+	}
+}
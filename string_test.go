@@ -0,0 +1,159 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fixedStrings struct {
+	Name string   `gopack:"bytes=4"`
+	Code []byte   `gopack:"bytes=3,fill=space"`
+	Call string   `gopack:"bytes=5,charset=sixbit"`
+	Tags []string `gopack:"bytes=2,charset=ascii7,len=2"`
+}
+
+type varStrings struct {
+	N    uint8  `gopack:"5"`
+	Text string `gopack:"lenfield=N,charset=ascii7"`
+	Call []byte `gopack:"len=3,charset=sixbit"`
+}
+
+func TestFixedStrings(t *testing.T) {
+	l := LayoutOf(struct {
+		Name string `gopack:"bytes=4"`
+		Call string `gopack:"bytes=5,charset=sixbit"`
+	}{})
+	if l.Bits != 62 || l.Fields[0].BitWidth != 32 || l.Fields[1].BitWidth != 30 {
+		t.Errorf("Unexpected layout %+v", l)
+	}
+
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		v := fixedStrings{"ab", []byte("XYZ"), "K9 AB", []string{"hi", "x"}}
+		expect := make([]byte, 16)
+		w := NewBitWriter(expect, order)
+		for _, ch := range []byte("ab\x00\x00") {
+			w.WriteUnsigned(uint64(ch), 8)
+		}
+		for _, ch := range []byte("XYZ") {
+			w.WriteUnsigned(uint64(ch), 8)
+		}
+		for _, ch := range []byte("K9 AB") {
+			w.WriteUnsigned(uint64(ch-' '), 6)
+		}
+		w.WriteUnsigned(2, 2)
+		for _, ch := range []byte("hix\x00") {
+			w.WriteUnsigned(uint64(ch), 7)
+		}
+		testVarLen(t, c, v, &fixedStrings{}, expect[:bitsToBytes(uint64(w.Pos()))])
+
+		// Trailing fill characters are removed
+		v = fixedStrings{"", []byte("A"), "Z", nil}
+		b := c.AppendPack(nil, v)
+		if !bytes.Equal(b[4:7], []byte("A  ")) {
+			t.Errorf("Expected %q; got %q", "A  ", b[4:7])
+		}
+		var got fixedStrings
+		c.Unpack(b, &got)
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("%v: Expected %+v; got %+v", order, v, got)
+		}
+	}
+}
+
+func TestVarStrings(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		v := varStrings{3, "a~Z", []byte("OK")}
+		expect := make([]byte, 6)
+		w := NewBitWriter(expect, order)
+		w.WriteUnsigned(3, 5)
+		for _, ch := range []byte("a~Z") {
+			w.WriteUnsigned(uint64(ch), 7)
+		}
+		w.WriteUnsigned(2, 3)
+		for _, ch := range []byte("OK") {
+			w.WriteUnsigned(uint64(ch-' '), 6)
+		}
+		testVarLen(t, c, v, &varStrings{}, expect[:bitsToBytes(uint64(w.Pos()))])
+	}
+
+	// []byte fields reuse their storage
+	b := AppendPack(nil, varStrings{Call: []byte("AB")})
+	call := make([]byte, 0, 8)
+	v := varStrings{Call: call}
+	Unpack(b, &v)
+	if string(v.Call) != "AB" || &v.Call[0] != &call[:1][0] {
+		t.Errorf("Expected the slice to be reused; got %q", v.Call)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	err := PackE(make([]byte, 16), fixedStrings{Name: "abcde"})
+	if e, ok := err.(Error); !ok || e.Path != "Name" || e.Offset != 0 || e.Width != 32 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	err = PackE(make([]byte, 16), fixedStrings{Call: "AbC"})
+	if e, ok := err.(Error); !ok || e.Path != "Call" || e.Offset != 62 || e.Width != 6 || !errors.Is(err, Unrepresentable) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	testError(t, Unrepresentable, "gopack: Tags[1]: character '\\u0080' not in charset ascii7", func() {
+		Pack(make([]byte, 16), fixedStrings{Tags: []string{"", "\x80"}})
+	})
+	testError(t, BadLength, "gopack: Text: length field N is 1, but length is 2", func() {
+		Pack(make([]byte, 4), varStrings{N: 1, Text: "ab"})
+	})
+	testError(t, ShortBuffer, "gopack: Text: buffer too small (2) for 3 characters", func() {
+		Unpack([]byte{3, 0}, &varStrings{})
+	})
+
+	testError(t, BadTag, "gopack: S: bad struct tag: exactly one of \"bytes\", \"len\", and \"lenfield\" is required", func() {
+		Pack(nil, struct{ S string }{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: exactly one of \"bytes\", \"len\", and \"lenfield\" is required", func() {
+		Pack(nil, struct {
+			S string `gopack:"bytes=2,len=3"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: width given for string field", func() {
+		Pack(nil, struct {
+			S string `gopack:"8,bytes=2"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: unknown charset \"ebcdic\"", func() {
+		Pack(nil, struct {
+			S []byte `gopack:"bytes=2,charset=ebcdic"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: option \"fill\" requires \"bytes\"", func() {
+		Pack(nil, struct {
+			S string `gopack:"len=2,fill=space"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: unknown fill \"zero\"", func() {
+		Pack(nil, struct {
+			S string `gopack:"bytes=2,fill=zero"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: fill '\\x00' not in charset sixbit", func() {
+		Pack(nil, struct {
+			S string `gopack:"bytes=2,fill=nul,charset=sixbit"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: string length too small (0)", func() {
+		Pack(nil, struct {
+			S string `gopack:"bytes=0"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: unsupported option \"signed\"", func() {
+		Pack(nil, struct {
+			S string `gopack:"bytes=2,signed"`
+		}{})
+	})
+}
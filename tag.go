@@ -0,0 +1,202 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tagOptions is the parsed form of a field's
+// "gopack" struct tag, which has the form
+//
+//	`gopack:"<width>,<key>=<value>,<flag>"`
+//
+// All parts are optional. The width may also
+// be given as "width=<width>".
+type tagOptions struct {
+	width string
+	// Whether the width was given
+	// as "width=<width>"
+	widthKey bool
+	// Flags are stored with a value of "".
+	opts map[string]string
+}
+
+func parseTag(path string, lsb uint64, st reflect.StructTag) (tagOptions, error) {
+	var t tagOptions
+	str := st.Get("gopack")
+	if str == "" {
+		return t, nil
+	}
+	for i, part := range strings.Split(str, ",") {
+		key, val := part, ""
+		if j := strings.Index(part, "="); j >= 0 {
+			key, val = part[:j], part[j+1:]
+		} else if i == 0 && !flagOptions[part] {
+			t.width = part
+			continue
+		}
+		if key == "" {
+			return t, newFieldError(BadTag, path, lsb, 0, "bad struct tag: empty option")
+		}
+		if key == "width" {
+			if t.width != "" {
+				return t, newFieldError(BadTag, path, lsb, 0, "bad struct tag: duplicate option %q", key)
+			}
+			t.width, t.widthKey = val, true
+			continue
+		}
+		if _, ok := t.opts[key]; ok {
+			return t, newFieldError(BadTag, path, lsb, 0, "bad struct tag: duplicate option %q", key)
+		}
+		if t.opts == nil {
+			t.opts = make(map[string]string)
+		}
+		t.opts[key] = val
+	}
+	return t, nil
+}
+
+// Options which take no value. These may be
+// given first in a tag without a width.
+var flagOptions = map[string]bool{"signed": true, "check": true, "optional": true}
+
+// Options which are handled when the field
+// is placed within its struct, and so are
+// allowed on any field.
+var placementOptions = []string{"offset"}
+
+// Returns an error if t has any options
+// other than those in keys or placementOptions.
+func (t tagOptions) allow(path string, lsb uint64, keys ...string) error {
+	keys = append(keys, placementOptions...)
+outer:
+	for key := range t.opts {
+		for _, k := range keys {
+			if key == k {
+				continue outer
+			}
+		}
+		return newFieldError(BadTag, path, lsb, 0, "bad struct tag: unsupported option %q", key)
+	}
+	return nil
+}
+
+// Returns tag with the given options removed
+// from its "gopack" key, so that the rest of
+// the tag can be applied to the elements of
+// a field.
+func withoutOptions(tag reflect.StructTag, keys ...string) reflect.StructTag {
+	var parts []string
+outer:
+	for _, part := range strings.Split(tag.Get("gopack"), ",") {
+		for _, key := range keys {
+			if strings.HasPrefix(part, key+"=") || part == key {
+				continue outer
+			}
+		}
+		parts = append(parts, part)
+	}
+	return reflect.StructTag("gopack:" + strconv.Quote(strings.Join(parts, ",")))
+}
+
+func (t tagOptions) has(key string) bool {
+	_, ok := t.opts[key]
+	return ok
+}
+
+// Reports whether the "offset" option of t, on
+// a field of type typ, is the value offset of
+// a quantized float (see getFloatEncoding)
+// rather than the placement of the field. This
+// is the case for float fields (and arrays,
+// slices, and pointers of them) unless the
+// width is given as "width=<width>", as it is
+// when placing fields.
+// Custom types have no float encoding.
+func (t tagOptions) floatOffset(typ reflect.Type) bool {
+	for !isCustom(typ) {
+		k := typ.Kind()
+		if k != reflect.Array && k != reflect.Slice && k != reflect.Ptr {
+			break
+		}
+		typ = typ.Elem()
+	}
+	if isCustom(typ) {
+		return false
+	}
+	switch typ.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return t.has("offset") && !t.widthKey
+	}
+	return false
+}
+
+// Returns the width specified in the tag,
+// or max if none was specified. The width
+// must be in the range [1, max].
+func (t tagOptions) getWidth(path string, lsb uint64, typ reflect.Type, max uint64) (uint64, error) {
+	if t.width == "" {
+		return max, nil
+	}
+
+	n64, err := strconv.ParseInt(t.width, 10, 0)
+	n := int(n64)
+	if err != nil {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: %w", err)
+	} else if n > int(max) {
+		return 0, newFieldError(TagTooWide, path, lsb, uint64(n),
+			"struct tag too wide for type %s (%d)", typ, n)
+	} else if n < 1 {
+		return 0, newFieldError(TagTooSmall, path, lsb, 0,
+			"struct tag too small (%d)", n)
+	}
+	return uint64(n), nil
+}
+
+// Returns the value of the integer option
+// key, or def if the option is not present.
+func (t tagOptions) getInt(path string, lsb uint64, key string, def int) (int, error) {
+	str, ok := t.opts[key]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option %q: %w", key, err)
+	}
+	return n, nil
+}
+
+// Returns the value of the unsigned integer
+// option key, or 0 if the option is not present.
+func (t tagOptions) getUint(path string, lsb uint64, key string) (uint64, error) {
+	str, ok := t.opts[key]
+	if !ok {
+		return 0, nil
+	}
+	u, err := strconv.ParseUint(str, 0, 64)
+	if err != nil {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option %q: %w", key, err)
+	}
+	return u, nil
+}
+
+// Returns the value of the floating-point
+// option key, or def if the option is not
+// present.
+func (t tagOptions) getFloat(path string, lsb uint64, key string, def float64) (float64, error) {
+	str, ok := t.opts[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, newFieldError(BadTag, path, lsb, 0, "bad struct tag: option %q: %w", key, err)
+	}
+	return f, nil
+}
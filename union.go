@@ -0,0 +1,244 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// The variants registered for an interface
+// type, which may not change once a union
+// field of that type has been planned
+type variantSet struct {
+	types map[int64]reflect.Type
+	keys  map[reflect.Type]int64
+	used  bool
+}
+
+var (
+	variantsMu sync.Mutex
+	// Keyed by interface type
+	variants = make(map[reflect.Type]*variantSet)
+)
+
+// RegisterVariant registers the type of v as the
+// variant of the interface type iface which is
+// selected by the value key of a union field's
+// switch field (see Pack). v must implement iface,
+// and is typically a struct or a pointer to one.
+//
+// The variants of iface must all be registered
+// before any type with a field of type iface is
+// first packed or unpacked, such as from an init
+// function. RegisterVariant panics if iface is not
+// an interface type, if v does not implement it, if
+// key or the type of v is already registered for
+// iface, or if it is called too late.
+func RegisterVariant(iface reflect.Type, key int64, v interface{}) {
+	if iface.Kind() != reflect.Interface {
+		panic(newError(UnsupportedType, "%v is not an interface type", iface))
+	}
+	typ := reflect.TypeOf(v)
+	if typ == nil || !typ.Implements(iface) {
+		panic(newError(UnsupportedType, "variant type %v does not implement %v", typ, iface))
+	}
+
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	set := variants[iface]
+	if set == nil {
+		set = &variantSet{types: make(map[int64]reflect.Type), keys: make(map[reflect.Type]int64)}
+		variants[iface] = set
+	}
+	switch {
+	case set.used:
+		panic(newError(BadVariant, "variant %v of %v registered after first use", typ, iface))
+	case set.types[key] != nil:
+		panic(newError(BadVariant, "duplicate key %v for variants %v and %v of %v", key, set.types[key], typ, iface))
+	}
+	if k, ok := set.keys[typ]; ok {
+		panic(newError(BadVariant, "variant %v of %v already registered with key %v", typ, iface, k))
+	}
+	set.types[key] = typ
+	set.keys[typ] = key
+}
+
+// Returns the variants registered for iface,
+// which can no longer be changed, or nil if
+// there are none.
+func variantsOf(iface reflect.Type) *variantSet {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	set := variants[iface]
+	if set != nil {
+		set.used = true
+	}
+	return set
+}
+
+// Returns the types of the variants registered
+// for iface without marking them as used, so
+// that more may still be registered.
+func variantTypes(iface reflect.Type) []reflect.Type {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	set := variants[iface]
+	if set == nil {
+		return nil
+	}
+	types := make([]reflect.Type, 0, len(set.keys))
+	for typ := range set.keys {
+		types = append(types, typ)
+	}
+	return types
+}
+
+// A union is an interface field which holds one
+// of the variants registered for its type. The
+// variant is selected by the earlier integer
+// field named by its "switch" option, and packed
+// in place of the field. Like a slice, a union
+// is variable-size, and is resolved against its
+// siblings by its struct.
+func makeUnionPlan(name, path string, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	t, err := parseTag(path, 0, tag)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.allow(path, 0, "switch"); err != nil {
+		return nil, err
+	}
+	if t.width != "" {
+		return nil, newFieldError(BadTag, path, 0, 0, "bad struct tag: width given for %v field", typ)
+	}
+	p := &plan{kind: planUnion, name: name, path: path, typ: typ, varSize: true, lenField: -1, switchName: t.opts["switch"]}
+	if p.switchName == "" {
+		return nil, newFieldError(BadTag, path, 0, 0, "bad struct tag: interface field needs a switch option")
+	}
+	set := variantsOf(typ)
+	if set == nil {
+		return nil, newFieldError(UnsupportedType, path, 0, 0, "no variants registered for %v", typ)
+	}
+
+	// Plan the variants in order, so that
+	// any error is reported consistently
+	keys := make([]int64, 0, len(set.types))
+	for key := range set.types {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	p.variants = make(map[int64]*plan, len(keys))
+	for _, key := range keys {
+		vt := set.types[key]
+		if containsType(vt, typ, make(map[reflect.Type]bool)) {
+			return nil, newFieldError(UnsupportedType, path, 0, 0, "recursive type %v", typ)
+		}
+		v, err := makeFieldPlan(name, path, 0, vt, "")
+		if err != nil {
+			return nil, err
+		}
+		p.variants[key] = v
+	}
+	p.variantKeys = set.keys
+	return p, nil
+}
+
+// Resolves the switch option of f, which
+// is field i of p, to the index of the
+// field holding its key.
+func (p *plan) resolveSwitch(f *plan, i int) error {
+	for j, g := range p.fields[:i] {
+		if g != nil && g.name == f.switchName && (g.kind == planUnsigned || g.kind == planSigned) {
+			f.switchField = j
+			return nil
+		}
+	}
+	return newFieldError(BadTag, f.path, 0, 0, "bad struct tag: switch %q is not an earlier integer field", f.switchName)
+}
+
+// Returns the key held in the integer field
+// v. Unsigned values which overflow an int64
+// wrap around, but remain distinct.
+func keyOf(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	}
+	return int64(v.Uint())
+}
+
+// Returns the plan for the variant held in
+// the union field v, or nil if v is nil or
+// holds a type which is not registered.
+func (p *plan) variantOf(v reflect.Value) *plan {
+	if v.IsNil() {
+		return nil
+	}
+	key, ok := p.variantKeys[v.Elem().Type()]
+	if !ok {
+		return nil
+	}
+	return p.variants[key]
+}
+
+// Returns a varPacker for f, the union field
+// i of p, which is passed the struct (see
+// fieldVarPacker).
+func (p *plan) unionVarPacker(order BitOrder, i int, f *plan) (varPacker, error) {
+	packers := make(map[int64]varPacker, len(f.variants))
+	for key, v := range f.variants {
+		var err error
+		if packers[key], err = v.varPacker(order); err != nil {
+			return nil, err
+		}
+	}
+	j := f.switchField
+	return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+		v := s.Field(i)
+		if v.IsNil() {
+			return 0, newFieldError(BadVariant, f.path, off, 0, "nil interface value")
+		}
+		key, ok := f.variantKeys[v.Elem().Type()]
+		if !ok {
+			return 0, newFieldError(BadVariant, f.path, off, 0, "unregistered variant type %v", v.Elem().Type())
+		}
+		if k := keyOf(s.Field(j)); k != key {
+			return 0, newFieldError(BadVariant, f.path, off, 0, "switch field %s is %v, but variant %v has key %v",
+				f.switchName, s.Field(j), v.Elem().Type(), key)
+		}
+		return packers[key](b, off, v.Elem())
+	}, nil
+}
+
+// The inverse of unionVarPacker
+func (p *plan) unionVarUnpacker(order BitOrder, i int, f *plan) (varUnpacker, error) {
+	unpackers := make(map[int64]varUnpacker, len(f.variants))
+	for key, v := range f.variants {
+		var err error
+		if unpackers[key], err = v.varUnpacker(order); err != nil {
+			return nil, err
+		}
+	}
+	j := f.switchField
+	return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+		key := keyOf(s.Field(j))
+		u, ok := unpackers[key]
+		if !ok {
+			return 0, newFieldError(BadVariant, f.path, off, 0, "no variant of %v with key %v", f.typ, s.Field(j))
+		}
+		// Unpack into a new value, since the
+		// value held in an interface cannot
+		// be modified
+		v := reflect.New(f.variants[key].typ).Elem()
+		off, err := u(b, off, v)
+		if err != nil {
+			return 0, err
+		}
+		s.Field(i).Set(v)
+		return off, nil
+	}, nil
+}
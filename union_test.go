@@ -0,0 +1,197 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type unionBody interface{}
+
+type unionPing struct {
+	Seq uint8 `gopack:"6"`
+}
+
+type unionData struct {
+	N    uint8   `gopack:"3"`
+	Vals []uint8 `gopack:"5,lenfield=N"`
+}
+
+// A variant registered as a pointer
+type unionAck struct{ OK bool }
+
+type unionFrame struct {
+	Kind    int8 `gopack:"4"`
+	HasBody bool
+	Body    unionBody `gopack:"if=HasBody,switch=Kind"`
+	Tail    uint8     `gopack:"3"`
+}
+
+type unionUnused interface{}
+
+// Never used, so that more
+// variants may be registered
+type unionOpen interface{}
+
+// Only reached by types which fail
+// to plan, so that its variants are
+// never marked as used
+type unionLate interface{}
+
+// Returns the interface type
+// pointed to by ptr.
+func ifaceOf(ptr interface{}) reflect.Type {
+	return reflect.TypeOf(ptr).Elem()
+}
+
+func init() {
+	RegisterVariant(ifaceOf((*unionBody)(nil)), 1, unionPing{})
+	RegisterVariant(ifaceOf((*unionBody)(nil)), 2, unionData{})
+	RegisterVariant(ifaceOf((*unionBody)(nil)), -1, &unionAck{})
+	RegisterVariant(ifaceOf((*unionUnused)(nil)), 0, unionPing{})
+	RegisterVariant(ifaceOf((*unionOpen)(nil)), 1, unionPing{})
+	RegisterVariant(ifaceOf((*unionLate)(nil)), 1, unionPing{})
+}
+
+func TestUnionFields(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		expect := make([]byte, 2)
+		w := NewBitWriter(expect, order)
+		w.WriteUnsigned(1, 4)
+		w.WriteBool(true)
+		w.WriteUnsigned(45, 6)
+		w.WriteUnsigned(5, 3)
+		testVarLen(t, c, unionFrame{1, true, unionPing{45}, 5}, &unionFrame{}, expect)
+
+		expect = make([]byte, 3)
+		w = NewBitWriter(expect, order)
+		w.WriteUnsigned(2, 4)
+		w.WriteBool(true)
+		w.WriteUnsigned(2, 3)
+		w.WriteUnsigned(7, 5)
+		w.WriteUnsigned(31, 5)
+		w.WriteUnsigned(5, 3)
+		testVarLen(t, c, unionFrame{2, true, unionData{2, []uint8{7, 31}}, 5}, &unionFrame{}, expect)
+
+		expect = make([]byte, 2)
+		w = NewBitWriter(expect, order)
+		w.WriteSigned(-1, 4)
+		w.WriteBool(true)
+		w.WriteBool(true)
+		w.WriteUnsigned(5, 3)
+		testVarLen(t, c, unionFrame{-1, true, &unionAck{true}, 5}, &unionFrame{}, expect)
+
+		expect = make([]byte, 1)
+		w = NewBitWriter(expect, order)
+		w.WriteUnsigned(3, 4)
+		w.WriteBool(false)
+		w.WriteUnsigned(5, 3)
+		testVarLen(t, c, unionFrame{3, false, nil, 5}, &unionFrame{}, expect)
+	}
+
+	// Unpack replaces the variant held
+	v := unionFrame{Body: unionData{}}
+	Unpack(AppendPack(nil, unionFrame{1, true, unionPing{3}, 0}), &v)
+	if v.Body != (unionPing{3}) {
+		t.Errorf("Unexpected value %+v", v)
+	}
+}
+
+func TestUnionErrors(t *testing.T) {
+	err := PackE(make([]byte, 2), unionFrame{Kind: 2, HasBody: true, Body: unionPing{}})
+	if e, ok := err.(Error); !ok || e.Path != "Body" || e.Offset != 5 || !errors.Is(err, BadVariant) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	if err.Error() != "gopack: Body: switch field Kind is 2, but variant gopack.unionPing has key 1" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	testError(t, BadVariant, "gopack: Body: nil interface value", func() {
+		Pack(make([]byte, 2), unionFrame{Kind: 1, HasBody: true})
+	})
+	testError(t, BadVariant, "gopack: Body: unregistered variant type *gopack.unionPing", func() {
+		Pack(make([]byte, 2), unionFrame{Kind: 1, HasBody: true, Body: &unionPing{}})
+	})
+	testError(t, BadVariant, "gopack: Body: no variant of gopack.unionBody with key 3", func() {
+		Unpack([]byte{0x13, 0}, &unionFrame{})
+	})
+	testError(t, BadLength, "gopack: Body.Vals: length field N is 1, but length is 0", func() {
+		Pack(make([]byte, 2), unionFrame{Kind: 2, HasBody: true, Body: unionData{N: 1}})
+	})
+
+	testError(t, BadTag, "gopack: B: bad struct tag: interface field needs a switch option", func() {
+		Pack(nil, struct{ B unionBody }{})
+	})
+	testError(t, BadTag, `gopack: B: bad struct tag: switch "K" is not an earlier integer field`, func() {
+		Pack(nil, struct {
+			K bool
+			B unionBody `gopack:"switch=K"`
+		}{})
+	})
+	testError(t, UnsupportedType, "gopack: B: no variants registered for interface {}", func() {
+		Pack(nil, struct {
+			K uint8
+			B interface{} `gopack:"switch=K"`
+		}{})
+	})
+	testError(t, UnsupportedType, "gopack: B[0]: non-packable type gopack.unionBody", func() {
+		Pack(nil, struct {
+			B [2]unionBody
+		}{})
+	})
+
+	Pack(make([]byte, 2), struct {
+		K uint8
+		B unionUnused `gopack:"switch=K"`
+	}{B: unionPing{}})
+	testError(t, BadVariant, "gopack: variant gopack.unionData of gopack.unionUnused registered after first use", func() {
+		RegisterVariant(ifaceOf((*unionUnused)(nil)), 1, unionData{})
+	})
+	// Checking a pointer type for recursion
+	// does not count as a use of unionLate
+	if err := PackE(nil, struct {
+		P *struct {
+			C chan int
+			K uint8
+			B unionLate `gopack:"switch=K"`
+		}
+	}{}); !errors.Is(err, UnsupportedType) {
+		t.Errorf("Unexpected error %v", err)
+	}
+	variantsMu.Lock()
+	used := variants[ifaceOf((*unionLate)(nil))].used
+	variantsMu.Unlock()
+	if used {
+		t.Errorf("Variants of unionLate marked as used")
+	}
+	testError(t, BadVariant, "gopack: duplicate key 1 for variants gopack.unionPing and gopack.unionAck of gopack.unionOpen", func() {
+		RegisterVariant(ifaceOf((*unionOpen)(nil)), 1, unionAck{})
+	})
+	testError(t, BadVariant, "gopack: variant gopack.unionPing of gopack.unionOpen already registered with key 1", func() {
+		RegisterVariant(ifaceOf((*unionOpen)(nil)), 2, unionPing{})
+	})
+	testError(t, UnsupportedType, "gopack: variant type int does not implement interface { String() string }", func() {
+		RegisterVariant(ifaceOf((*interface{ String() string })(nil)), 0, 0)
+	})
+}
+
+type unionNode interface{}
+
+type unionTree struct {
+	K    uint8
+	Kids unionNode `gopack:"switch=K"`
+}
+
+func init() {
+	RegisterVariant(ifaceOf((*unionNode)(nil)), 0, unionTree{})
+}
+
+func TestUnionRecursive(t *testing.T) {
+	testError(t, UnsupportedType, "gopack: Kids: recursive type gopack.unionNode", func() {
+		Pack(nil, unionTree{})
+	})
+}
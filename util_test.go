@@ -56,10 +56,10 @@ func nOnes(n int) uint64 {
 // 	for i := 0; i < typ.NumField(); i++ {
 // 		switch typ.Field(i).Type.Kind() {
 // 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-// 			n, _ := getFieldWidth(typ.Field(i))
+// 			n, _ := getFieldWidth("", 0, typ.Field(i))
 // 			val.Field(i).SetInt(randInt64Bits(uint8(n)))
 // 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-// 			n, _ := getFieldWidth(typ.Field(i))
+// 			n, _ := getFieldWidth("", 0, typ.Field(i))
 // 			val.Field(i).SetUint(randUint64Bits(uint8(n)))
 // 		case reflect.Bool:
 // 			val.Field(i).SetBool(randBool())
@@ -73,20 +73,32 @@ func nOnes(n int) uint64 {
 func randInstance(typ reflect.Type) reflect.Value {
 	val := reflect.New(typ).Elem()
 	for i := 0; i < typ.NumField(); i++ {
-		switch typ.Field(i).Type.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			n, _ := getFieldWidth(typ.Field(i))
-			val.Field(i).SetInt(randInt64Bits(uint8(n)))
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			n, _ := getFieldWidth(typ.Field(i))
-			val.Field(i).SetUint(randUint64Bits(uint8(n)))
-		case reflect.Bool:
-			val.Field(i).SetBool(randBool())
-		case reflect.Struct:
-			val.Field(i).Set(randInstance(typ.Field(i).Type))
-		default:
-			panic(fmt.Sprint("Cannot generate type:", typ.Field(i).Type))
-		}
+		randFill(val.Field(i), typ.Field(i).Tag)
 	}
 	return val
 }
+
+func randFill(val reflect.Value, tag reflect.StructTag) {
+	typ := val.Type()
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, _ := getFieldWidth("", 0, typ, tag)
+		val.SetInt(randInt64Bits(uint8(n)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, _ := getFieldWidth("", 0, typ, tag)
+		val.SetUint(randUint64Bits(uint8(n)))
+	case reflect.Bool:
+		val.SetBool(randBool())
+	case reflect.Float32, reflect.Float64:
+		// Only unquantized floats are supported
+		val.SetFloat(rand.NormFloat64() * 1e6)
+	case reflect.Struct:
+		val.Set(randInstance(typ))
+	case reflect.Array:
+		for i := 0; i < typ.Len(); i++ {
+			randFill(val.Index(i), tag)
+		}
+	default:
+		panic(fmt.Sprint("Cannot generate type:", typ))
+	}
+}
@@ -0,0 +1,621 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+)
+
+// Variable-size values (slices, strings with
+// a variable length, optional pointers,
+// conditional fields, unions, and structs,
+// arrays, and pointers which contain them)
+// cannot be packed by closures with fixed
+// offsets. Instead, they are packed by
+// varPackers, which track the offset at run
+// time. The fixed-size parts of such a value
+// are still packed by ordinary packers, built
+// as if placed at offset 0, and then shifted
+// into place.
+
+// A varPacker packs v starting at bit offset
+// off of b, and returns the offset at which v
+// ends. Any error has an offset relative to
+// the start of b.
+type varPacker func(b []byte, off uint64, v reflect.Value) (uint64, error)
+
+// A varUnpacker is the inverse of a varPacker.
+type varUnpacker func(b []byte, off uint64, v reflect.Value) (uint64, error)
+
+// The Err of a ShortBuffer error from a
+// varUnpacker. need is a lower bound on the
+// length of b, so that a Decoder can read
+// that much of the stream before unpacking
+// again, rather than one byte at a time.
+type shortBuffer struct {
+	msg  string
+	need int
+}
+
+func (e shortBuffer) Error() string { return e.msg }
+
+func newShortBufferError(path string, off, width uint64, need int, format string, a ...interface{}) Error {
+	return Error{Kind: ShortBuffer, Path: path, Offset: off, Width: width, Err: shortBuffer{fmt.Sprintf(format, a...), need}}
+}
+
+// Returns the number of bytes which hold n
+// values of w bits starting at bit offset off,
+// or the largest int if that would overflow.
+func bytesFor(off, n, w uint64) int {
+	const maxInt = int(^uint(0) >> 1)
+	if w != 0 && n > (math.MaxUint64-off)/w {
+		return maxInt
+	}
+	bits := off + n*w
+	if bits/8 >= uint64(maxInt) {
+		return maxInt
+	}
+	return bitsToBytes(bits)
+}
+
+// A segment of a variable-size struct is either
+// a run of fixed-size fields, fields[start:end],
+// whose offsets are relative to the start of the
+// run, or a single variable-size field.
+type segment struct {
+	start, end int
+	// The size of a run
+	bits     uint64
+	varField bool
+}
+
+func makeVarCachedPacker(pl *plan, strct reflect.Type, order BitOrder) cachedPacker {
+	p, err := pl.varPacker(order)
+	if err != nil {
+		return cachedPacker{err: err}
+	}
+	ptrType := strct.Kind() == reflect.Ptr
+	return cachedPacker{
+		packer: func(b []byte, v reflect.Value) error {
+			if ptrType {
+				v = v.Elem()
+			}
+			_, err := p(b, 0, v)
+			return err
+		},
+		bits:  pl.bits,
+		bytes: bitsToBytes(pl.bits),
+		size: func(v reflect.Value) uint64 {
+			if ptrType {
+				v = v.Elem()
+			}
+			return pl.size(v)
+		},
+	}
+}
+
+func makeVarCachedUnpacker(pl *plan, strct reflect.Type, order BitOrder) cachedUnpacker {
+	u, err := pl.varUnpacker(order)
+	if err != nil {
+		return cachedUnpacker{err: err}
+	}
+	c := cachedUnpacker{unpacker: noOpUnpacker, bits: pl.bits, bytes: bitsToBytes(pl.bits), variable: true, unpackFrom: u}
+	// As in makeCachedUnpacker, unpacking
+	// into a non-pointer is a no-op
+	if strct.Kind() == reflect.Ptr {
+		c.unpacker = func(b []byte, v reflect.Value) error {
+			_, err := u(b, 0, v.Elem())
+			return err
+		}
+	}
+	return c
+}
+
+func makeSlicePlan(name, path string, typ reflect.Type, tag reflect.StructTag) (*plan, error) {
+	p := &plan{kind: planSlice, name: name, path: path, typ: typ, varSize: true, lenField: -1}
+	t, err := parseTag(path, 0, tag)
+	if err != nil {
+		return nil, err
+	}
+	if isRecursive(typ.Elem()) {
+		return nil, newFieldError(UnsupportedType, path, 0, 0, "recursive type %v", typ.Elem())
+	}
+	switch {
+	case t.has("len") && t.has("lenfield"):
+		return nil, newFieldError(BadTag, path, 0, 0, "bad struct tag: both len and lenfield given")
+	case t.has("len"):
+		if p.lenBits, err = getLengthBits(path, t); err != nil {
+			return nil, err
+		}
+	case t.opts["lenfield"] != "":
+		p.lenName = t.opts["lenfield"]
+	default:
+		return nil, newFieldError(BadTag, path, 0, 0, "bad struct tag: slice needs a len or lenfield option")
+	}
+
+	// The rest of the tag applies to each element
+	elem, err := makeFieldPlan("", "", 0, typ.Elem(), withoutOptions(tag, "len", "lenfield"))
+	if err != nil {
+		return nil, relocateError(err, path+"[]", 0)
+	}
+	if elem.bits == 0 {
+		return nil, newFieldError(UnsupportedType, path, 0, 0, "slice of zero-size type %v", typ.Elem())
+	}
+	p.elem = elem
+	p.bits = p.lenBits
+	return p, nil
+}
+
+// Returns the width of the length prefix
+// given by the "len" option of t.
+func getLengthBits(path string, t tagOptions) (uint64, error) {
+	n, err := t.getInt(path, 0, "len", 0)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > 64 {
+		return 0, newFieldError(BadTag, path, 0, 0, "bad struct tag: length prefix out of range (%d)", n)
+	}
+	return uint64(n), nil
+}
+
+// Resolves the lenfield option of f, which
+// is field i of p, to the index of the
+// field holding its length.
+func (p *plan) resolveLength(f *plan, i int) error {
+	if f.lenName == "" {
+		return nil
+	}
+	for j, g := range p.fields[:i] {
+		if g != nil && g.name == f.lenName && (g.kind == planUnsigned || g.kind == planSigned) {
+			f.lenField = j
+			return nil
+		}
+	}
+	return newFieldError(BadTag, f.path, 0, 0, "bad struct tag: lenfield %q is not an earlier integer field", f.lenName)
+}
+
+// Returns the number of bits
+// occupied by v when packed.
+func (p *plan) size(v reflect.Value) uint64 {
+	if !p.varSize {
+		return p.bits
+	}
+	var n uint64
+	switch p.kind {
+	case planStruct:
+		for _, s := range p.segs {
+			if s.varField {
+				n += p.fieldSize(v, s.start, p.fields[s.start])
+			} else {
+				n += s.bits
+			}
+		}
+	case planArray:
+		for i, f := range p.fields {
+			n += f.size(v.Index(i))
+		}
+	case planString:
+		n = p.lenBits + uint64(v.Len())*uint64(p.str.charset.width)
+	case planPointer:
+		n = p.pointerSize(v)
+	case planUnion:
+		// Packing will fail if there is
+		// no variant
+		if variant := p.variantOf(v); variant != nil {
+			n = variant.size(v.Elem())
+		}
+	case planSlice:
+		n = p.lenBits
+		if !p.elem.varSize {
+			return n + uint64(v.Len())*p.elem.bits
+		}
+		for i := 0; i < v.Len(); i++ {
+			n += p.elem.size(v.Index(i))
+		}
+	}
+	return n
+}
+
+// Returns the number of bits occupied by
+// f, the variable-size field i of p, within
+// the struct v when packed.
+func (p *plan) fieldSize(v reflect.Value, i int, f *plan) uint64 {
+	if f.kind != planCond {
+		return f.size(v.Field(i))
+	}
+	if !v.Field(f.condField).Bool() {
+		return 0
+	}
+	return p.fieldSize(v, i, f.elem)
+}
+
+func (p *plan) varPacker(order BitOrder) (varPacker, error) {
+	if !p.varSize {
+		f, err := p.packer(order, false)
+		if err != nil {
+			return nil, err
+		}
+		bits, scratch := p.bits, newScratch(p.bits)
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			return off + bits, packAt(order, f, bits, scratch, b, off, v)
+		}, nil
+	}
+	switch p.kind {
+	case planStruct:
+		return p.structVarPacker(order)
+	case planPointer:
+		return p.pointerVarPacker(order)
+	case planArray:
+		elems := make([]varPacker, len(p.fields))
+		for i, f := range p.fields {
+			var err error
+			if elems[i], err = f.varPacker(order); err != nil {
+				return nil, err
+			}
+		}
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			for i, f := range elems {
+				var err error
+				if off, err = f(b, off, v.Index(i)); err != nil {
+					return 0, err
+				}
+			}
+			return off, nil
+		}, nil
+	default:
+		body, err := p.bodyVarPacker(order)
+		if err != nil {
+			return nil, err
+		}
+		put, _ := bitsFuncs(order)
+		max := uint64(1)<<(p.lenBits-1)<<1 - 1
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			n := uint64(v.Len())
+			if n > max {
+				return 0, newFieldError(Overflow, p.path, off, p.lenBits, "length out of range: max %v; got %v", max, n)
+			}
+			put(b, off, uint8(p.lenBits), n)
+			return body(b, off+p.lenBits, v)
+		}, nil
+	}
+}
+
+func (p *plan) varUnpacker(order BitOrder) (varUnpacker, error) {
+	if !p.varSize {
+		f, err := p.unpacker(order, false)
+		if err != nil {
+			return nil, err
+		}
+		bits, scratch := p.bits, newScratch(p.bits)
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			return off + bits, unpackAt(order, f, bits, scratch, b, off, v)
+		}, nil
+	}
+	switch p.kind {
+	case planStruct:
+		return p.structVarUnpacker(order)
+	case planPointer:
+		return p.pointerVarUnpacker(order)
+	case planArray:
+		elems := make([]varUnpacker, len(p.fields))
+		for i, f := range p.fields {
+			var err error
+			if elems[i], err = f.varUnpacker(order); err != nil {
+				return nil, err
+			}
+		}
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			for i, f := range elems {
+				var err error
+				if off, err = f(b, off, v.Index(i)); err != nil {
+					return 0, err
+				}
+			}
+			return off, nil
+		}, nil
+	default:
+		body, err := p.bodyVarUnpacker(order)
+		if err != nil {
+			return nil, err
+		}
+		_, get := bitsFuncs(order)
+		return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+			if need := bitsToBytes(off + p.lenBits); len(b) < need {
+				return 0, newShortBufferError(p.path, off, p.lenBits, need, "buffer too small (%v; need %v)", len(b), need)
+			}
+			n := get(b, off, uint8(p.lenBits))
+			return body(b, off+p.lenBits, v, n)
+		}, nil
+	}
+}
+
+func (p *plan) structVarPacker(order BitOrder) (varPacker, error) {
+	segs := make([]varPacker, len(p.segs))
+	for k, seg := range p.segs {
+		if !seg.varField {
+			packers := make([]packer, len(p.fields))
+			for i, f := range p.fields {
+				packers[i] = noOpPacker
+				if f != nil && seg.start <= i && i < seg.end {
+					var err error
+					if packers[i], err = f.packer(order, false); err != nil {
+						return nil, err
+					}
+				}
+			}
+			run, bits, scratch := makeCallAllPackers(packers, false), seg.bits, newScratch(seg.bits)
+			segs[k] = func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+				return off + bits, packAt(order, run, bits, scratch, b, off, s)
+			}
+			continue
+		}
+
+		var err error
+		if segs[k], err = p.fieldVarPacker(order, seg.start, p.fields[seg.start]); err != nil {
+			return nil, err
+		}
+	}
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		for _, seg := range segs {
+			var err error
+			if off, err = seg(b, off, v); err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+	}, nil
+}
+
+func (p *plan) structVarUnpacker(order BitOrder) (varUnpacker, error) {
+	segs := make([]varUnpacker, len(p.segs))
+	for k, seg := range p.segs {
+		if !seg.varField {
+			unpackers := make([]unpacker, len(p.fields))
+			for i, f := range p.fields {
+				unpackers[i] = noOpUnpacker
+				if f != nil && seg.start <= i && i < seg.end {
+					var err error
+					if unpackers[i], err = f.unpacker(order, false); err != nil {
+						return nil, err
+					}
+				}
+			}
+			run, bits, scratch := makeCallAllUnpackers(unpackers, false), seg.bits, newScratch(seg.bits)
+			segs[k] = func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+				return off + bits, unpackAt(order, run, bits, scratch, b, off, s)
+			}
+			continue
+		}
+
+		var err error
+		if segs[k], err = p.fieldVarUnpacker(order, seg.start, p.fields[seg.start]); err != nil {
+			return nil, err
+		}
+	}
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		for _, seg := range segs {
+			var err error
+			if off, err = seg(b, off, v); err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+	}, nil
+}
+
+// Returns a varPacker for f, the variable-size
+// field i of p. Unlike other varPackers, it is
+// passed the struct rather than the field, so
+// that it can refer to the field's siblings.
+func (p *plan) fieldVarPacker(order BitOrder, i int, f *plan) (varPacker, error) {
+	switch {
+	case f.kind == planCond:
+		field, err := p.fieldVarPacker(order, i, f.elem)
+		if err != nil {
+			return nil, err
+		}
+		c := f.condField
+		return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+			if !s.Field(c).Bool() {
+				return off, nil
+			}
+			return field(b, off, s)
+		}, nil
+	case f.kind == planUnion:
+		return p.unionVarPacker(order, i, f)
+	case f.lenName == "":
+		field, err := f.varPacker(order)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+			return field(b, off, s.Field(i))
+		}, nil
+	}
+	body, err := f.bodyVarPacker(order)
+	if err != nil {
+		return nil, err
+	}
+	j := f.lenField
+	return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+		v := s.Field(i)
+		if n, ok := lengthOf(s.Field(j)); !ok || n != uint64(v.Len()) {
+			return 0, newFieldError(BadLength, f.path, off, 0, "length field %s is %v, but length is %v",
+				f.lenName, s.Field(j), v.Len())
+		}
+		return body(b, off, v)
+	}, nil
+}
+
+// The inverse of fieldVarPacker
+func (p *plan) fieldVarUnpacker(order BitOrder, i int, f *plan) (varUnpacker, error) {
+	switch {
+	case f.kind == planCond:
+		field, err := p.fieldVarUnpacker(order, i, f.elem)
+		if err != nil {
+			return nil, err
+		}
+		c := f.condField
+		return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+			if !s.Field(c).Bool() {
+				v := s.Field(i)
+				v.Set(reflect.Zero(v.Type()))
+				return off, nil
+			}
+			return field(b, off, s)
+		}, nil
+	case f.kind == planUnion:
+		return p.unionVarUnpacker(order, i, f)
+	case f.lenName == "":
+		field, err := f.varUnpacker(order)
+		if err != nil {
+			return nil, err
+		}
+		return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+			return field(b, off, s.Field(i))
+		}, nil
+	}
+	body, err := f.bodyVarUnpacker(order)
+	if err != nil {
+		return nil, err
+	}
+	j := f.lenField
+	return func(b []byte, off uint64, s reflect.Value) (uint64, error) {
+		n, ok := lengthOf(s.Field(j))
+		if !ok {
+			return 0, newFieldError(BadLength, f.path, off, 0, "length field %s is negative (%v)", f.lenName, s.Field(j))
+		}
+		return body(b, off, s.Field(i), n)
+	}, nil
+}
+
+// Returns a varPacker which packs the elements
+// of a slice or the characters of a string,
+// without its length.
+func (p *plan) bodyVarPacker(order BitOrder) (varPacker, error) {
+	if p.kind == planString {
+		return p.stringBodyPacker(order), nil
+	}
+	elem, err := p.elem.varPacker(order)
+	if err != nil {
+		return nil, err
+	}
+	return func(b []byte, off uint64, v reflect.Value) (uint64, error) {
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if off, err = elem(b, off, v.Index(i)); err != nil {
+				return 0, relocateError(err, elemPath(p.path, i), 0)
+			}
+		}
+		return off, nil
+	}, nil
+}
+
+// Returns a function which unpacks n elements
+// into a slice, reusing the slice's storage if
+// it has the capacity, or n characters into a
+// string.
+func (p *plan) bodyVarUnpacker(order BitOrder) (func(b []byte, off uint64, v reflect.Value, n uint64) (uint64, error), error) {
+	if p.kind == planString {
+		return p.stringBodyUnpacker(order), nil
+	}
+	elem, err := p.elem.varUnpacker(order)
+	if err != nil {
+		return nil, err
+	}
+	return func(b []byte, off uint64, v reflect.Value, n uint64) (uint64, error) {
+		// Check that the buffer could hold n
+		// elements before allocating them, so
+		// that a corrupt length cannot cause
+		// a huge allocation. Elements are at
+		// least one bit wide (see makeSlicePlan).
+		if n > (uint64(len(b))*8-off)/p.elem.bits {
+			need := bytesFor(off, n, p.elem.bits)
+			return 0, newShortBufferError(p.path, off, 0, need, "buffer too small (%v) for %v elements", len(b), n)
+		}
+		l := int(n)
+		if v.Cap() >= l {
+			v.SetLen(l)
+		} else {
+			v.Set(reflect.MakeSlice(v.Type(), l, l))
+		}
+		for i := 0; i < l; i++ {
+			var err error
+			if off, err = elem(b, off, v.Index(i)); err != nil {
+				return 0, relocateError(err, elemPath(p.path, i), 0)
+			}
+		}
+		return off, nil
+	}, nil
+}
+
+// Returns a pool of scratch buffers large
+// enough to hold bits bits, for packAt and
+// unpackAt to use when off is not byte-aligned.
+func newScratch(bits uint64) *sync.Pool {
+	n := bitsToBytes(bits)
+	return &sync.Pool{New: func() interface{} {
+		buf := make([]byte, n)
+		return &buf
+	}}
+}
+
+// Pack v into b at bit offset off using p,
+// a packer built for offset 0 which packs
+// bits bits. scratch is from newScratch(bits).
+func packAt(order BitOrder, p packer, bits uint64, scratch *sync.Pool, b []byte, off uint64, v reflect.Value) error {
+	i, phase := off/8, uint8(off%8)
+	var err error
+	if phase == 0 {
+		err = p(b[i:], v)
+	} else {
+		// Pack into a zeroed scratch buffer,
+		// and then shift the bits into place
+		buf := scratch.Get().(*[]byte)
+		for j := range *buf {
+			(*buf)[j] = 0
+		}
+		if err = p(*buf, v); err == nil {
+			shiftInBits(order, b[i:], phase, *buf)
+		}
+		scratch.Put(buf)
+	}
+	return relocateError(err, "", off)
+}
+
+// The inverse of packAt
+func unpackAt(order BitOrder, u unpacker, bits uint64, scratch *sync.Pool, b []byte, off uint64, v reflect.Value) error {
+	need := bitsToBytes(off + bits)
+	if len(b) < need {
+		return newShortBufferError("", off, 0, need, "buffer too small (%v; need %v)", len(b), need)
+	}
+	i, phase := off/8, uint8(off%8)
+	var err error
+	if phase == 0 {
+		err = u(b[i:], v)
+	} else {
+		// shiftOutBits fills all of buf
+		buf := scratch.Get().(*[]byte)
+		shiftOutBits(order, *buf, phase, b[i:need])
+		err = u(*buf, v)
+		scratch.Put(buf)
+	}
+	return relocateError(err, "", off)
+}
+
+// Returns the length held in the integer
+// field v, or false if it is negative.
+func lengthOf(v reflect.Value) (uint64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		return uint64(n), n >= 0
+	}
+	return v.Uint(), true
+}
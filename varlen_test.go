@@ -0,0 +1,331 @@
+// Copyright 2014 The Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+type varEntry struct {
+	A uint8 `gopack:"3"`
+	B bool
+}
+
+type varRecord struct {
+	Count uint8      `gopack:"4"`
+	Items []varEntry `gopack:"lenfield=Count"`
+	Tail  uint8      `gopack:"4"`
+}
+
+type varPrefixed struct {
+	Flag bool
+	Vals []uint16    `gopack:"12,len=6"`
+	Recs []varRecord `gopack:"len=3"`
+}
+
+func TestVarLenFields(t *testing.T) {
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		c := Config{Order: order}
+		rec := varRecord{2, []varEntry{{5, true}, {1, false}}, 0xA}
+		expect := make([]byte, 2)
+		w := NewBitWriter(expect, order)
+		w.WriteUnsigned(2, 4)
+		w.WriteUnsigned(5, 3)
+		w.WriteBool(true)
+		w.WriteUnsigned(1, 3)
+		w.WriteBool(false)
+		w.WriteUnsigned(0xA, 4)
+		testVarLen(t, c, rec, &varRecord{}, expect)
+
+		pre := varPrefixed{true, []uint16{0xABC, 0x123}, []varRecord{{}, rec}}
+		expect = make([]byte, 9)
+		w = NewBitWriter(expect, order)
+		w.WriteBool(true)
+		w.WriteUnsigned(2, 6)
+		w.WriteUnsigned(0xABC, 12)
+		w.WriteUnsigned(0x123, 12)
+		w.WriteUnsigned(2, 3)
+		w.WriteUnsigned(0, 8)
+		w.WriteUnsigned(2, 4)
+		w.WriteUnsigned(5, 3)
+		w.WriteBool(true)
+		w.WriteUnsigned(1, 3)
+		w.WriteBool(false)
+		w.WriteUnsigned(0xA, 4)
+		testVarLen(t, c, pre, &varPrefixed{}, expect[:bitsToBytes(uint64(w.Pos()))])
+
+		testVarLen(t, c, varPrefixed{}, &varPrefixed{}, []byte{0, 0})
+	}
+}
+
+// Pack v, check that it packs to expect, and
+// then unpack it into ptr, which should then
+// point to a value equal to v.
+func testVarLen(t *testing.T, c Config, v, ptr interface{}, expect []byte) {
+	if sz := c.PackedSizeof(v); sz != len(expect) {
+		t.Errorf("%v: Expected a packed size of %v; got %v", c.Order, len(expect), sz)
+	}
+	b := make([]byte, len(expect)+1)
+	for i := range b {
+		b[i] = 0xFF
+	}
+	if err := c.PackE(b, v); err != nil {
+		t.Fatalf("%v: Unexpected error: %v", c.Order, err)
+	}
+	if !bytes.Equal(b[:len(expect)], expect) || b[len(expect)] != 0xFF {
+		t.Errorf("%v: Expected %#v; got %#v", c.Order, expect, b)
+	}
+	if got := c.AppendPack([]byte{1}, v); !bytes.Equal(got[1:], expect) {
+		t.Errorf("%v: Expected %#v; got %#v", c.Order, expect, got[1:])
+	}
+	if err := c.UnpackE(expect, ptr); err != nil {
+		t.Fatalf("%v: Unexpected error: %v", c.Order, err)
+	}
+	got := reflect.ValueOf(ptr).Elem().Interface()
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("%v: Expected %+v; got %+v", c.Order, v, got)
+	}
+}
+
+func TestVarLenReuse(t *testing.T) {
+	b := make([]byte, 3)
+	Pack(b, varRecord{1, []varEntry{{3, true}}, 0})
+	items := make([]varEntry, 4, 8)
+	rec := varRecord{Items: items}
+	Unpack(b, &rec)
+	if len(rec.Items) != 1 || &rec.Items[0] != &items[0] {
+		t.Errorf("Expected the slice to be reused; got %v", rec.Items)
+	}
+	Pack(b, varRecord{3, make([]varEntry, 3), 0})
+	Unpack(b, &rec)
+	if len(rec.Items) != 3 || &rec.Items[0] != &items[0] {
+		t.Errorf("Expected the slice to be reused; got %v", rec.Items)
+	}
+}
+
+func TestVarLenCodec(t *testing.T) {
+	c, err := CodecFor(reflect.TypeOf(varRecord{}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Size() != -1 {
+		t.Errorf("Expected a size of -1; got %v", c.Size())
+	}
+	b := make([]byte, 2)
+	rec := varRecord{1, []varEntry{{7, false}}, 3}
+	if err := c.Pack(b, rec); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var rec2 varRecord
+	if err := c.Unpack(b, &rec2); err != nil || !reflect.DeepEqual(rec, rec2) {
+		t.Errorf("Expected %v; got %v (error %v)", rec, rec2, err)
+	}
+
+	// Values are written one after another
+	var buf bytes.Buffer
+	e := Config{Contiguous: true}.NewEncoder(&buf)
+	e.Encode(varPrefixed{Vals: []uint16{1}})
+	e.Encode(rec)
+	e.Flush()
+	expect := make([]byte, 5)
+	w := NewBitWriter(expect, LSBFirst)
+	w.WriteBool(false)
+	w.WriteUnsigned(1, 6)
+	w.WriteUnsigned(1, 12)
+	w.WriteUnsigned(0, 3)
+	w.WriteUnsigned(1, 4)
+	w.WriteUnsigned(7, 3)
+	w.WriteBool(false)
+	w.WriteUnsigned(3, 4)
+	if !bytes.Equal(buf.Bytes(), expect) {
+		t.Errorf("Expected %#v; got %#v", expect, buf.Bytes())
+	}
+}
+
+// Larger than a Decoder's read buffer
+type varLarge struct {
+	Data []uint8 `gopack:"len=16"`
+}
+
+// With a length too large for a Decoder
+// to buffer
+type varHuge struct {
+	Data []uint8 `gopack:"len=32"`
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += n
+	return n, err
+}
+
+func TestVarLenDecoder(t *testing.T) {
+	vals := []interface{}{
+		varRecord{2, []varEntry{{5, true}, {1, false}}, 0xA},
+		varPrefixed{true, []uint16{1, 4095}, []varRecord{{}}},
+		varLarge{make([]uint8, 3*streamBufSize)},
+		varRecord{Tail: 3},
+	}
+	for i := range vals[2].(varLarge).Data {
+		vals[2].(varLarge).Data[i] = uint8(i)
+	}
+	for _, order := range []BitOrder{LSBFirst, MSBFirst} {
+		for _, contiguous := range []bool{false, true} {
+			c := Config{Order: order, Contiguous: contiguous}
+			var buf bytes.Buffer
+			e := c.NewEncoder(&buf)
+			for _, v := range vals {
+				if err := e.Encode(v); err != nil {
+					t.Fatalf("%v: Unexpected error: %v", c, err)
+				}
+			}
+			e.Flush()
+
+			// Read a byte at a time so that
+			// values span several reads
+			d := c.NewDecoder(iotest.OneByteReader(&buf))
+			for i, v := range vals {
+				got := reflect.New(reflect.TypeOf(v))
+				if err := d.Decode(got.Interface()); err != nil {
+					t.Fatalf("%v: value %v: Unexpected error: %v", c, i, err)
+				}
+				if !reflect.DeepEqual(got.Elem().Interface(), v) {
+					t.Errorf("%v: value %v: Expected %v; got %v", c, i, v, got.Elem())
+				}
+			}
+			if err := d.Decode(&varRecord{}); err != io.EOF {
+				t.Errorf("%v: Expected io.EOF; got %v", c, err)
+			}
+		}
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte{0x13}))
+	if err := d.Decode(&varRecord{}); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF; got %v", err)
+	}
+
+	// A corrupt length must not make the
+	// Decoder read the rest of the stream
+	zeros := &countingReader{r: io.LimitReader(zeroReader{}, 1<<20)}
+	d = NewDecoder(io.MultiReader(bytes.NewReader([]byte{0xFF, 0xFF, 0xFF, 0xFF}), zeros))
+	err := d.Decode(&varHuge{})
+	if e, ok := err.(Error); !ok || e.Path != "Data" || !errors.Is(err, BadLength) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	if zeros.n > streamBufSize {
+		t.Errorf("Expected to read at most %v bytes; read %v", streamBufSize, zeros.n)
+	}
+
+	// The end of a value which cannot be
+	// unpacked is unknown, so the error
+	// is returned from then on
+	d = NewDecoder(bytes.NewReader([]byte{0x13, 0, 0}))
+	for i := 0; i < 2; i++ {
+		if err := d.Decode(&unionFrame{}); !errors.Is(err, BadVariant) {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}
+}
+
+func TestVarLenErrors(t *testing.T) {
+	err := PackE(make([]byte, 2), varRecord{Count: 1, Items: make([]varEntry, 2)})
+	if e, ok := err.(Error); !ok || e.Path != "Items" || e.Offset != 4 || !errors.Is(err, BadLength) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	err = PackE(make([]byte, 3), varRecord{Count: 2, Items: []varEntry{{}, {A: 8}}})
+	if e, ok := err.(Error); !ok || e.Path != "Items[1].A" || e.Offset != 8 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	err = PackE(make([]byte, 100), varPrefixed{Vals: make([]uint16, 64)})
+	if e, ok := err.(Error); !ok || e.Path != "Vals" || e.Offset != 1 || !errors.Is(err, Overflow) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	testError(t, ShortBuffer, "gopack: buffer too small (2; need 3)", func() {
+		Pack(make([]byte, 2), varPrefixed{Vals: []uint16{1}})
+	})
+
+	// A length of 4 followed by too little data
+	b := []byte{0x04, 0x00}
+	var rec varRecord
+	err = UnpackE(b[:1], &rec)
+	if e, ok := err.(Error); !ok || e.Path != "Items" || !errors.Is(err, ShortBuffer) {
+		t.Errorf("Unexpected error %#v", err)
+	}
+	testError(t, ShortBuffer, "gopack: Items: buffer too small (2) for 4 elements", func() {
+		Unpack(b, &rec)
+	})
+	testError(t, ShortBuffer, "gopack: Vals: buffer too small (2) for 63 elements", func() {
+		Unpack([]byte{0x7E, 0}, &varPrefixed{})
+	})
+	testError(t, BadLength, "gopack: Items: length field N is negative (-1)", func() {
+		Unpack([]byte{0x0F}, &struct {
+			N     int8    `gopack:"4"`
+			Items []uint8 `gopack:"lenfield=N"`
+		}{})
+	})
+
+	testError(t, BadTag, "gopack: S: bad struct tag: slice needs a len or lenfield option", func() {
+		Pack(nil, struct{ S []uint8 }{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: both len and lenfield given", func() {
+		Pack(nil, struct {
+			N uint8
+			S []uint8 `gopack:"len=4,lenfield=N"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: S: bad struct tag: length prefix out of range (65)", func() {
+		Pack(nil, struct {
+			S []uint8 `gopack:"len=65"`
+		}{})
+	})
+	testError(t, BadTag, `gopack: S: bad struct tag: lenfield "N" is not an earlier integer field`, func() {
+		Pack(nil, struct {
+			S []uint8 `gopack:"lenfield=N"`
+			N uint8
+		}{})
+	})
+	testError(t, TagTooWide, "gopack: S[]: struct tag too wide for type uint8 (9)", func() {
+		Pack(nil, struct {
+			S []uint8 `gopack:"9,len=4"`
+		}{})
+	})
+	testError(t, UnsupportedType, "gopack: S: slice of zero-size type struct {}", func() {
+		Pack(nil, struct {
+			S []struct{} `gopack:"len=4"`
+		}{})
+	})
+	testError(t, BadTag, "gopack: B: bad struct tag: offset after variable-size field", func() {
+		Pack(nil, struct {
+			S []uint8 `gopack:"len=4"`
+			B bool    `gopack:"offset=9"`
+		}{})
+	})
+
+	testError(t, UnsupportedType, "gopack: variable-size type gopack.varRecord has no fixed layout", func() {
+		LayoutOf(varRecord{})
+	})
+	testError(t, UnsupportedType, "gopack: slice of variable-size type gopack.varRecord", func() {
+		PackSlice(nil, []varRecord{})
+	})
+}